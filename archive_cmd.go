@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"voice_server/internal/logger"
+	"voice_server/internal/speaker"
+)
+
+// runExport 声纹库导出子命令
+// 用法：voice_server export -o speakers.jsonl.gz [-uid u1] [-agent-id a1]（不传 -uid 时导出整个 Collection）
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	configPath := fs.String("config", "config.json", "配置文件路径")
+	output := fs.String("o", "", "导出文件路径（- 表示标准输出）")
+	uid := fs.String("uid", "", "只导出指定 uid 的说话人")
+	agentID := fs.String("agent-id", "", "只导出指定 agent_id 的说话人")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *output == "" {
+		fmt.Fprintln(os.Stderr, "export: -o is required")
+		fs.Usage()
+		return 2
+	}
+
+	deps, err := initCommandDeps("export", *configPath, true)
+	if err != nil {
+		return 1
+	}
+	defer deps.SpeakerManager.Close()
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			logger.Errorf("Failed to create output file: %v", err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	header, err := deps.SpeakerManager.Export(w, *uid, *agentID, nil)
+	if err != nil {
+		logger.Errorf("Export failed: %v", err)
+		return 1
+	}
+
+	logger.Infof("✅ Exported %d points to %s", header.Count, *output)
+	return 0
+}
+
+// runImport 声纹库导入子命令
+// 用法：voice_server import -i speakers.jsonl.gz [-mode merge|replace] [-dry-run] [-allow-model-mismatch]
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	configPath := fs.String("config", "config.json", "配置文件路径")
+	input := fs.String("i", "", "导入文件路径（- 表示标准输入）")
+	mode := fs.String("mode", speaker.ImportModeMerge, "导入模式：merge 或 replace")
+	dryRun := fs.Bool("dry-run", false, "只校验和统计，不写入")
+	allowModelMismatch := fs.Bool("allow-model-mismatch", false, "允许模型名称不一致（维度仍必须一致）")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *input == "" {
+		fmt.Fprintln(os.Stderr, "import: -i is required")
+		fs.Usage()
+		return 2
+	}
+
+	deps, err := initCommandDeps("import", *configPath, true)
+	if err != nil {
+		return 1
+	}
+	defer deps.SpeakerManager.Close()
+
+	var r io.Reader = os.Stdin
+	if *input != "-" {
+		file, err := os.Open(*input)
+		if err != nil {
+			logger.Errorf("Failed to open input file: %v", err)
+			return 1
+		}
+		defer file.Close()
+		r = file
+	}
+
+	report, err := deps.SpeakerManager.Import(r, speaker.ImportOptions{
+		Mode:               *mode,
+		DryRun:             *dryRun,
+		AllowModelMismatch: *allowModelMismatch,
+	})
+	if report != nil {
+		output, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(output))
+	}
+	if err != nil {
+		logger.Errorf("Import failed: %v", err)
+		return 1
+	}
+	return 0
+}
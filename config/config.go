@@ -40,6 +40,7 @@ type Config struct {
 	Speaker struct {
 		Enabled          bool    `mapstructure:"enabled"`
 		ModelPath        string  `mapstructure:"model_path"`
+		ModelID          string  `mapstructure:"model_id"`
 		NumThreads       int     `mapstructure:"num_threads"`
 		Provider         string  `mapstructure:"provider"`
 		Threshold        float32 `mapstructure:"threshold"`
@@ -47,10 +48,81 @@ type Config struct {
 		SaveAudioOnFinish bool   `mapstructure:"save_audio_on_finish"`
 		AudioSaveDir     string  `mapstructure:"audio_save_dir"`
 		VectorDB         struct {
-			Host           string `mapstructure:"host"`
-			Port           int    `mapstructure:"port"`
-			CollectionName string `mapstructure:"collection_name"`
+			Host               string `mapstructure:"host"`
+			Port               int    `mapstructure:"port"`
+			CollectionName     string `mapstructure:"collection_name"`
+			LegacyModel        string `mapstructure:"legacy_model"`
+			APIKey             string `mapstructure:"api_key"`
+			UseTLS             bool   `mapstructure:"use_tls"`
+			CACertFile         string `mapstructure:"ca_cert_file"`
+			InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+			TimeoutMs          int    `mapstructure:"timeout_ms"`
+			MaxRetries         int    `mapstructure:"max_retries"`
+			RetryBackoffMs     int    `mapstructure:"retry_backoff_ms"`
+			BreakerThreshold   int    `mapstructure:"breaker_threshold"`
+			BreakerCooldownMs  int    `mapstructure:"breaker_cooldown_ms"`
 		} `mapstructure:"vector_db"`
+		ScoreNorm struct {
+			Enabled          bool    `mapstructure:"enabled"`
+			Method           string  `mapstructure:"method"`
+			CohortDir        string  `mapstructure:"cohort_dir"`
+			CohortCollection string  `mapstructure:"cohort_collection"`
+			TopN             int     `mapstructure:"top_n"`
+			Threshold        float32 `mapstructure:"threshold"`
+		} `mapstructure:"score_norm"`
+		Calibration struct {
+			RootDir string `mapstructure:"root_dir"`
+		} `mapstructure:"calibration"`
+		Admin struct {
+			Token string `mapstructure:"token"`
+		} `mapstructure:"admin"`
+		DuplicateCheck struct {
+			Enabled   bool    `mapstructure:"enabled"`
+			Threshold float32 `mapstructure:"threshold"`
+			Action    string  `mapstructure:"action"`
+		} `mapstructure:"duplicate_check"`
+		ExtractorPool struct {
+			PoolSize          int `mapstructure:"pool_size"`
+			NumThreads        int `mapstructure:"num_threads"`
+			CheckoutTimeoutMs int `mapstructure:"checkout_timeout_ms"`
+		} `mapstructure:"extractor_pool"`
+		Challenge struct {
+			TTLSeconds       int     `mapstructure:"ttl_seconds"`
+			Length           int     `mapstructure:"length"`
+			MaxCharErrorRate float32 `mapstructure:"max_char_error_rate"`
+		} `mapstructure:"challenge"`
+		AudioArchive struct {
+			MaxAgeHours          int `mapstructure:"max_age_hours"`
+			MaxTotalMB           int `mapstructure:"max_total_mb"`
+			SweepIntervalMinutes int `mapstructure:"sweep_interval_minutes"`
+		} `mapstructure:"audio_archive"`
+		Audit struct {
+			Enabled bool `mapstructure:"enabled"`
+			File    struct {
+				Enabled    bool   `mapstructure:"enabled"`
+				Dir        string `mapstructure:"dir"`
+				MaxSizeMB  int    `mapstructure:"max_size_mb"`
+				MaxBackups int    `mapstructure:"max_backups"`
+				MaxAgeDays int    `mapstructure:"max_age_days"`
+				Compress   bool   `mapstructure:"compress"`
+			} `mapstructure:"file"`
+			Webhook struct {
+				Enabled   bool              `mapstructure:"enabled"`
+				URL       string            `mapstructure:"url"`
+				Headers   map[string]string `mapstructure:"headers"`
+				TimeoutMs int               `mapstructure:"timeout_ms"`
+				QueueSize int               `mapstructure:"queue_size"`
+			} `mapstructure:"webhook"`
+		} `mapstructure:"audit"`
+		Streaming struct {
+			MinSpeechMs        int `mapstructure:"min_speech_ms"`
+			EdgePaddingMs      int `mapstructure:"edge_padding_ms"`
+			MaxRegisterSeconds int `mapstructure:"max_register_seconds"`
+		} `mapstructure:"streaming"`
+		AutoEnroll struct {
+			Enabled    bool `mapstructure:"enabled"`
+			MaxSamples int  `mapstructure:"max_samples"`
+		} `mapstructure:"auto_enroll"`
 	} `mapstructure:"speaker"`
 	Audio struct {
 		SampleRate      int     `mapstructure:"sample_rate"`
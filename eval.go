@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"voice_server/config"
+	"voice_server/internal/bootstrap"
+	"voice_server/internal/logger"
+	"voice_server/internal/speaker"
+)
+
+// runEval 声纹阈值校准子命令
+// 用法：voice_server eval -dir trials/ [-enroll 1] [-criterion mindcf] [-p-target 0.01] [-thresholds 0.5,0.6] [-apply]
+// 标注数据目录结构：<dir>/<speaker_id>/*.wav
+func runEval(args []string) int {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	configPath := fs.String("config", "config.json", "配置文件路径")
+	dir := fs.String("dir", "", "标注数据目录（每个说话人一个子目录）")
+	enroll := fs.Int("enroll", 1, "每个说话人用于注册的文件数")
+	criterion := fs.String("criterion", speaker.CalibrationCriterionMinDCF, "推荐阈值依据：eer 或 mindcf")
+	pTarget := fs.Float64("p-target", 0.01, "DCF 目标先验概率")
+	costMiss := fs.Float64("cost-miss", 1, "DCF 漏检代价")
+	costFA := fs.Float64("cost-fa", 1, "DCF 误识代价")
+	thresholds := fs.String("thresholds", "", "需要报告 FAR/FRR 的候选阈值，逗号分隔")
+	apply := fs.Bool("apply", false, "将推荐阈值写回配置文件")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *dir == "" {
+		fmt.Fprintln(os.Stderr, "eval: -dir is required")
+		fs.Usage()
+		return 2
+	}
+
+	opts := speaker.CalibrationOptions{
+		Dir:              *dir,
+		EnrollPerSpeaker: *enroll,
+		PTarget:          *pTarget,
+		CostMiss:         *costMiss,
+		CostFA:           *costFA,
+		Criterion:        *criterion,
+	}
+	for _, field := range strings.Split(*thresholds, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(field, 32)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "eval: invalid threshold %q: %v\n", field, err)
+			return 2
+		}
+		opts.Thresholds = append(opts.Thresholds, float32(value))
+	}
+
+	deps, err := initCommandDeps("eval", *configPath, true)
+	if err != nil {
+		return 1
+	}
+	defer deps.SpeakerManager.Close()
+
+	report, err := deps.SpeakerManager.EvaluateTrials(opts)
+	if err != nil {
+		logger.Errorf("Calibration failed: %v", err)
+		return 1
+	}
+
+	if *apply {
+		if err := speaker.ApplyCalibration(deps.HotReloadMgr, report); err != nil {
+			logger.Errorf("Failed to apply recommended threshold: %v", err)
+			return 1
+		}
+	}
+
+	output, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(output))
+	return 0
+}
+
+// initCommandDeps 子命令公共初始化：加载配置（loadConfig 为 false 时沿用已加载的配置）、初始化日志和依赖，并确认声纹识别可用
+func initCommandDeps(name, configPath string, loadConfig bool) (*bootstrap.AppDependencies, error) {
+	if loadConfig {
+		if err := config.InitConfig(configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: failed to load configuration: %v\n", name, err)
+			return nil, err
+		}
+	}
+	initLogger()
+
+	deps, err := bootstrap.InitApp(&config.GlobalConfig)
+	if err != nil {
+		logger.Errorf("Failed to initialize app dependencies:%v", err)
+		return nil, err
+	}
+	if deps.SpeakerManager == nil {
+		logger.Errorf("Speaker recognition is not available, check speaker configuration")
+		return nil, fmt.Errorf("speaker recognition is not available")
+	}
+	return deps, nil
+}
@@ -150,13 +150,75 @@ func InitApp(cfg *config.Config) (*AppDependencies, error) {
 		if _, statErr := os.Stat(cfg.Speaker.ModelPath); !os.IsNotExist(statErr) {
 			speakerConfig := &speaker.Config{
 				ModelPath:  cfg.Speaker.ModelPath,
+				ModelID:    cfg.Speaker.ModelID,
 				NumThreads: cfg.Speaker.NumThreads,
 				Provider:   cfg.Speaker.Provider,
 				Threshold:  cfg.Speaker.Threshold,
 				DataDir:    cfg.Speaker.DataDir,
+				ScoreNorm: speaker.ScoreNormConfig{
+					Enabled:          cfg.Speaker.ScoreNorm.Enabled,
+					Method:           cfg.Speaker.ScoreNorm.Method,
+					CohortDir:        cfg.Speaker.ScoreNorm.CohortDir,
+					CohortCollection: cfg.Speaker.ScoreNorm.CohortCollection,
+					TopN:             cfg.Speaker.ScoreNorm.TopN,
+					Threshold:        cfg.Speaker.ScoreNorm.Threshold,
+				},
+				Calibration: speaker.CalibrationConfig{
+					RootDir: cfg.Speaker.Calibration.RootDir,
+				},
+				Admin: speaker.AdminConfig{
+					Token: cfg.Speaker.Admin.Token,
+				},
+				DuplicateCheck: speaker.DuplicateCheckConfig{
+					Enabled:   cfg.Speaker.DuplicateCheck.Enabled,
+					Threshold: cfg.Speaker.DuplicateCheck.Threshold,
+					Action:    cfg.Speaker.DuplicateCheck.Action,
+				},
+				ExtractorPool: speaker.ExtractorPoolConfig{
+					PoolSize:          cfg.Speaker.ExtractorPool.PoolSize,
+					NumThreads:        cfg.Speaker.ExtractorPool.NumThreads,
+					CheckoutTimeoutMs: cfg.Speaker.ExtractorPool.CheckoutTimeoutMs,
+				},
+				Challenge: speaker.ChallengeConfig{
+					TTLSeconds:       cfg.Speaker.Challenge.TTLSeconds,
+					Length:           cfg.Speaker.Challenge.Length,
+					MaxCharErrorRate: cfg.Speaker.Challenge.MaxCharErrorRate,
+				},
+				AudioArchive: speaker.AudioArchiveConfig{
+					MaxAgeHours:          cfg.Speaker.AudioArchive.MaxAgeHours,
+					MaxTotalMB:           cfg.Speaker.AudioArchive.MaxTotalMB,
+					SweepIntervalMinutes: cfg.Speaker.AudioArchive.SweepIntervalMinutes,
+				},
+				Audit: speaker.AuditConfig{
+					Enabled: cfg.Speaker.Audit.Enabled,
+					File: speaker.AuditFileConfig{
+						Enabled:    cfg.Speaker.Audit.File.Enabled,
+						Dir:        cfg.Speaker.Audit.File.Dir,
+						MaxSizeMB:  cfg.Speaker.Audit.File.MaxSizeMB,
+						MaxBackups: cfg.Speaker.Audit.File.MaxBackups,
+						MaxAgeDays: cfg.Speaker.Audit.File.MaxAgeDays,
+						Compress:   cfg.Speaker.Audit.File.Compress,
+					},
+					Webhook: speaker.AuditWebhookConfig{
+						Enabled:   cfg.Speaker.Audit.Webhook.Enabled,
+						URL:       cfg.Speaker.Audit.Webhook.URL,
+						Headers:   cfg.Speaker.Audit.Webhook.Headers,
+						TimeoutMs: cfg.Speaker.Audit.Webhook.TimeoutMs,
+						QueueSize: cfg.Speaker.Audit.Webhook.QueueSize,
+					},
+				},
+				Streaming: speaker.StreamingConfig{
+					MinSpeechMs:        cfg.Speaker.Streaming.MinSpeechMs,
+					EdgePaddingMs:      cfg.Speaker.Streaming.EdgePaddingMs,
+					MaxRegisterSeconds: cfg.Speaker.Streaming.MaxRegisterSeconds,
+				},
+				AutoEnroll: speaker.AutoEnrollConfig{
+					Enabled:    cfg.Speaker.AutoEnroll.Enabled,
+					MaxSamples: cfg.Speaker.AutoEnroll.MaxSamples,
+				},
 			}
 			// 设置 Qdrant 向量数据库配置（优先从环境变量读取，其次从配置文件读取）
-			// 环境变量命名：QDRANT_HOST, QDRANT_PORT, QDRANT_COLLECTION_NAME
+			// 环境变量命名：QDRANT_HOST, QDRANT_PORT, QDRANT_COLLECTION_NAME, QDRANT_API_KEY, QDRANT_USE_TLS, QDRANT_CA_CERT_FILE
 			if envHost := os.Getenv("QDRANT_HOST"); envHost != "" {
 				speakerConfig.VectorDB.Host = envHost
 				logger.Infof("Using Qdrant host from environment variable: %s", envHost)
@@ -182,11 +244,60 @@ func InitApp(cfg *config.Config) (*AppDependencies, error) {
 			} else {
 				speakerConfig.VectorDB.CollectionName = cfg.Speaker.VectorDB.CollectionName
 			}
+			speakerConfig.VectorDB.LegacyModel = cfg.Speaker.VectorDB.LegacyModel
+
+			// 鉴权和 TLS：QDRANT_API_KEY, QDRANT_USE_TLS, QDRANT_CA_CERT_FILE
+			if envAPIKey := os.Getenv("QDRANT_API_KEY"); envAPIKey != "" {
+				speakerConfig.VectorDB.APIKey = envAPIKey
+				logger.Infof("Using Qdrant API key from environment variable")
+			} else {
+				speakerConfig.VectorDB.APIKey = cfg.Speaker.VectorDB.APIKey
+			}
+
+			speakerConfig.VectorDB.UseTLS = cfg.Speaker.VectorDB.UseTLS
+			if envUseTLS := os.Getenv("QDRANT_USE_TLS"); envUseTLS != "" {
+				if useTLS, err := strconv.ParseBool(envUseTLS); err == nil {
+					speakerConfig.VectorDB.UseTLS = useTLS
+					logger.Infof("Using Qdrant TLS setting from environment variable: %v", useTLS)
+				} else {
+					logger.Warnf("Invalid QDRANT_USE_TLS environment variable: %s, using config file value", envUseTLS)
+				}
+			}
+
+			if envCACertFile := os.Getenv("QDRANT_CA_CERT_FILE"); envCACertFile != "" {
+				speakerConfig.VectorDB.CACertFile = envCACertFile
+				logger.Infof("Using Qdrant CA certificate from environment variable: %s", envCACertFile)
+			} else {
+				speakerConfig.VectorDB.CACertFile = cfg.Speaker.VectorDB.CACertFile
+			}
+			speakerConfig.VectorDB.InsecureSkipVerify = cfg.Speaker.VectorDB.InsecureSkipVerify
+
+			speakerConfig.VectorDB.TimeoutMs = cfg.Speaker.VectorDB.TimeoutMs
+			speakerConfig.VectorDB.MaxRetries = cfg.Speaker.VectorDB.MaxRetries
+			speakerConfig.VectorDB.RetryBackoffMs = cfg.Speaker.VectorDB.RetryBackoffMs
+			speakerConfig.VectorDB.BreakerThreshold = cfg.Speaker.VectorDB.BreakerThreshold
+			speakerConfig.VectorDB.BreakerCooldownMs = cfg.Speaker.VectorDB.BreakerCooldownMs
 
 			mgr, err := speaker.NewManager(speakerConfig, vadPool)
 			if err == nil {
 				speakerManager = mgr
+				// 挑战-应答验证复用全局识别器（recognition 未启用时该功能不可用）
+				if globalRecognizer != nil {
+					speakerManager.SetRecognizer(globalRecognizer)
+				}
 				speakerHandler = speaker.NewHandler(speakerManager)
+				speakerHandler.SetConfigWriter(hotReloadMgr)
+
+				// 阈值和重复声纹检测配置支持热加载（包括校准接口写回的推荐阈值）
+				hotReloadMgr.RegisterCallback("speaker", func() {
+					mgr.SetThreshold(config.GlobalConfig.Speaker.Threshold)
+					mgr.SetScoreNormThreshold(config.GlobalConfig.Speaker.ScoreNorm.Threshold)
+					mgr.SetDuplicateCheck(speaker.DuplicateCheckConfig{
+						Enabled:   config.GlobalConfig.Speaker.DuplicateCheck.Enabled,
+						Threshold: config.GlobalConfig.Speaker.DuplicateCheck.Threshold,
+						Action:    config.GlobalConfig.Speaker.DuplicateCheck.Action,
+					})
+				})
 			} else {
 				logger.Warnf("Failed to initialize speaker recognition module, continuing without it: %v", err)
 			}
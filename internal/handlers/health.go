@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"voice_server/internal/bootstrap"
+	"voice_server/internal/speaker"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -29,6 +30,11 @@ func HealthHandler(deps *bootstrap.AppDependencies) gin.HandlerFunc {
 		}
 		if deps.SpeakerManager != nil {
 			components["speaker"] = deps.SpeakerManager.GetStats("", "") // 传入空字符串获取全局统计
+			components["speaker_extractor_pool"] = deps.SpeakerManager.GetExtractorPoolStats()
+			components["speaker_vector_db"] = deps.SpeakerManager.GetVectorDBStatus()
+			if scoreNorm := deps.SpeakerManager.GetScoreNormStatus(); scoreNorm != nil {
+				components["speaker_score_norm"] = scoreNorm
+			}
 		} else {
 			components["speaker"] = map[string]interface{}{"status": "disabled"}
 		}
@@ -37,6 +43,12 @@ func HealthHandler(deps *bootstrap.AppDependencies) gin.HandlerFunc {
 		if deps.VADPool == nil || deps.SessionManager == nil || deps.RateLimiter == nil {
 			status = "initializing"
 			c.Status(503)
+		} else if deps.SpeakerManager != nil {
+			// Qdrant 不可用或冒名者集合未加载时声纹接口会失败，但服务本身仍可用
+			scoreNorm := deps.SpeakerManager.GetScoreNormStatus()
+			if deps.SpeakerManager.GetVectorDBStatus().State != speaker.QdrantStateReady || (scoreNorm != nil && !scoreNorm.Loaded) {
+				status = "degraded"
+			}
 		}
 
 		health := map[string]interface{}{
@@ -0,0 +1,271 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"voice_server/internal/logger"
+
+	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+)
+
+// ErrExtractorCheckoutTimeout 在超时时间内没有可用的声纹特征提取器
+var ErrExtractorCheckoutTimeout = fmt.Errorf("speaker extractor pool checkout timeout")
+
+// ExtractorPoolInterface 声纹特征提取器池接口
+type ExtractorPoolInterface interface {
+	// Initialize 初始化池
+	Initialize() error
+
+	// Get 获取提取器实例（超过等待时间返回 ErrExtractorCheckoutTimeout）
+	Get() (*ExtractorInstance, error)
+
+	// Put 归还提取器实例
+	Put(instance *ExtractorInstance)
+
+	// Dim 获取声纹特征维度
+	Dim() int
+
+	// GetStats 获取统计信息
+	GetStats() map[string]interface{}
+
+	// Shutdown 关闭池
+	Shutdown()
+}
+
+// ExtractorPoolConfig 声纹特征提取器池配置
+type ExtractorPoolConfig struct {
+	ModelConfig     *sherpa.SpeakerEmbeddingExtractorConfig // 每个提取器的配置（NumThreads 为单个提取器的线程数）
+	PoolSize        int
+	CheckoutTimeout time.Duration
+}
+
+// ExtractorInstance 声纹特征提取器实例
+// sherpa 的提取器不是线程安全的，同一时间只能被一个调用方使用
+type ExtractorInstance struct {
+	ID        int
+	Extractor *sherpa.SpeakerEmbeddingExtractor
+	LastUsed  int64
+	InUse     int32
+}
+
+// Destroy 销毁实例
+func (i *ExtractorInstance) Destroy() {
+	if i.Extractor != nil {
+		sherpa.DeleteSpeakerEmbeddingExtractor(i.Extractor)
+		i.Extractor = nil
+	}
+}
+
+// ExtractorPool 声纹特征提取器资源池
+type ExtractorPool struct {
+	instances []*ExtractorInstance
+	available chan *ExtractorInstance
+	config    *ExtractorPoolConfig
+	dim       int
+
+	// 统计信息
+	totalCheckouts int64
+	totalTimeouts  int64
+	totalActive    int64
+	totalWaitNanos int64
+	maxWaitNanos   int64
+
+	// 控制
+	mu     sync.RWMutex
+	closed bool // 已关闭：不再借出实例，归还的实例直接销毁
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewExtractorPool 创建新的声纹特征提取器池
+func NewExtractorPool(config *ExtractorPoolConfig) *ExtractorPool {
+	if config.PoolSize <= 0 {
+		config.PoolSize = 1
+	}
+	if config.CheckoutTimeout <= 0 {
+		config.CheckoutTimeout = 5 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ExtractorPool{
+		instances: make([]*ExtractorInstance, 0, config.PoolSize),
+		available: make(chan *ExtractorInstance, config.PoolSize),
+		config:    config,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Initialize 初始化提取器池（所有实例都创建成功才算成功，实例之间的维度必须一致）
+func (p *ExtractorPool) Initialize() error {
+	logger.Infof("🔧 Initializing speaker extractor pool with %d instances (num_threads=%d)...",
+		p.config.PoolSize, p.config.ModelConfig.NumThreads)
+
+	for i := 0; i < p.config.PoolSize; i++ {
+		extractor := sherpa.NewSpeakerEmbeddingExtractor(p.config.ModelConfig)
+		if extractor == nil {
+			p.Shutdown()
+			return fmt.Errorf("failed to create speaker embedding extractor %d", i)
+		}
+
+		instance := &ExtractorInstance{
+			ID:        i,
+			Extractor: extractor,
+			LastUsed:  time.Now().UnixNano(),
+		}
+		p.mu.Lock()
+		p.instances = append(p.instances, instance)
+		p.mu.Unlock()
+
+		if dim := extractor.Dim(); p.dim == 0 {
+			p.dim = dim
+		} else if dim != p.dim {
+			p.Shutdown()
+			return fmt.Errorf("speaker embedding extractor %d dimension mismatch: expected %d, got %d", i, p.dim, dim)
+		}
+
+		p.available <- instance
+	}
+
+	logger.Infof("🚀 Speaker extractor pool initialized with %d instances, embedding dim %d", p.config.PoolSize, p.dim)
+	return nil
+}
+
+// Get 获取提取器实例，所有实例都在使用时最多等待 CheckoutTimeout
+func (p *ExtractorPool) Get() (*ExtractorInstance, error) {
+	start := time.Now()
+	timer := time.NewTimer(p.config.CheckoutTimeout)
+	defer timer.Stop()
+
+	select {
+	case instance := <-p.available:
+		// 与 Shutdown 互斥：关闭后取到的实例不再借出
+		p.mu.RLock()
+		if p.closed {
+			p.mu.RUnlock()
+			instance.Destroy()
+			return nil, fmt.Errorf("speaker extractor pool is shutting down")
+		}
+		atomic.StoreInt32(&instance.InUse, 1)
+		p.mu.RUnlock()
+
+		atomic.StoreInt64(&instance.LastUsed, time.Now().UnixNano())
+		atomic.AddInt64(&p.totalCheckouts, 1)
+		atomic.AddInt64(&p.totalActive, 1)
+		p.recordWait(time.Since(start))
+		return instance, nil
+	case <-timer.C:
+		atomic.AddInt64(&p.totalTimeouts, 1)
+		logger.Warnf("⏰ Speaker extractor pool checkout timed out after %v (active: %d)",
+			p.config.CheckoutTimeout, atomic.LoadInt64(&p.totalActive))
+		return nil, ErrExtractorCheckoutTimeout
+	case <-p.ctx.Done():
+		return nil, fmt.Errorf("speaker extractor pool is shutting down")
+	}
+}
+
+// Put 归还提取器实例
+func (p *ExtractorPool) Put(instance *ExtractorInstance) {
+	if instance == nil {
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&instance.InUse, 1, 0) {
+		logger.Warnf("⚠️ Speaker extractor instance %d was not in use, cannot return", instance.ID)
+		return
+	}
+	atomic.StoreInt64(&instance.LastUsed, time.Now().UnixNano())
+	atomic.AddInt64(&p.totalActive, -1)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		// 池已关闭，关闭时仍在使用的实例在归还时销毁
+		instance.Destroy()
+		return
+	}
+	p.available <- instance // 容量等于实例数，不会阻塞
+}
+
+// recordWait 记录获取实例的等待时间
+func (p *ExtractorPool) recordWait(wait time.Duration) {
+	atomic.AddInt64(&p.totalWaitNanos, int64(wait))
+	for {
+		current := atomic.LoadInt64(&p.maxWaitNanos)
+		if int64(wait) <= current || atomic.CompareAndSwapInt64(&p.maxWaitNanos, current, int64(wait)) {
+			return
+		}
+	}
+}
+
+// Dim 获取声纹特征维度
+func (p *ExtractorPool) Dim() int {
+	return p.dim
+}
+
+// GetStats 获取统计信息
+func (p *ExtractorPool) GetStats() map[string]interface{} {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	checkouts := atomic.LoadInt64(&p.totalCheckouts)
+	avgWaitMs := float64(0)
+	if checkouts > 0 {
+		avgWaitMs = float64(atomic.LoadInt64(&p.totalWaitNanos)) / float64(checkouts) / float64(time.Millisecond)
+	}
+
+	return map[string]interface{}{
+		"pool_size":           p.config.PoolSize,
+		"num_threads":         p.config.ModelConfig.NumThreads,
+		"checkout_timeout_ms": p.config.CheckoutTimeout.Milliseconds(),
+		"total_instances":     len(p.instances),
+		"available_count":     len(p.available),
+		"active_count":        atomic.LoadInt64(&p.totalActive),
+		"total_checkouts":     checkouts,
+		"total_timeouts":      atomic.LoadInt64(&p.totalTimeouts),
+		"avg_wait_ms":         avgWaitMs,
+		"max_wait_ms":         float64(atomic.LoadInt64(&p.maxWaitNanos)) / float64(time.Millisecond),
+	}
+}
+
+// Shutdown 关闭提取器池
+// 只销毁空闲实例，仍在使用的实例由调用方归还时销毁，避免释放正在提取声纹的模型
+func (p *ExtractorPool) Shutdown() {
+	logger.Infof("🛑 Shutting down speaker extractor pool...")
+	p.cancel()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+
+	// 清空可用队列
+drain:
+	for {
+		select {
+		case instance := <-p.available:
+			instance.Destroy()
+		default:
+			break drain
+		}
+	}
+
+	// 初始化失败时可能有未放入队列的实例
+	inUse := 0
+	for _, instance := range p.instances {
+		if atomic.LoadInt32(&instance.InUse) == 1 {
+			inUse++
+			continue
+		}
+		instance.Destroy()
+	}
+	p.instances = nil
+
+	if inUse > 0 {
+		logger.Infof("✅ Speaker extractor pool shutdown complete, %d instances in use will be released when returned", inUse)
+		return
+	}
+	logger.Infof("✅ Speaker extractor pool shutdown complete")
+}
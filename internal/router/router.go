@@ -3,6 +3,7 @@ package router
 import (
 	"voice_server/internal/bootstrap"
 	"voice_server/internal/handlers"
+	"voice_server/internal/middleware"
 	"voice_server/internal/ws"
 
 	"github.com/gin-gonic/gin"
@@ -12,6 +13,7 @@ import (
 func NewRouter(deps *bootstrap.AppDependencies) *gin.Engine {
 	ginRouter := gin.New()
 	ginRouter.Use(gin.Recovery())
+	ginRouter.Use(middleware.RequestID())
 	// TODO: 根据需要注入 gin.Logger()
 
 	// 注册基础路由
@@ -0,0 +1,44 @@
+package session
+
+import "errors"
+
+// 音频处理错误分类，用 errors.Is 判断
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionClosed   = errors.New("session closed")
+	ErrInvalidAudio    = errors.New("invalid audio data")
+	ErrVADUnavailable  = errors.New("VAD unavailable")
+	ErrVADTimeout      = errors.New("VAD processing timeout")
+)
+
+// 错误码（WebSocket 错误消息中的 code 字段）
+const (
+	CodeSessionNotFound = "session_not_found"
+	CodeSessionClosed   = "session_closed"
+	CodeInvalidAudio    = "invalid_audio"
+	CodeVADUnavailable  = "vad_unavailable"
+	CodeVADTimeout      = "vad_timeout"
+	CodeInternal        = "internal"
+)
+
+// errorCodes 错误分类到错误码的映射
+var errorCodes = []struct {
+	kind error
+	code string
+}{
+	{ErrSessionNotFound, CodeSessionNotFound},
+	{ErrSessionClosed, CodeSessionClosed},
+	{ErrInvalidAudio, CodeInvalidAudio},
+	{ErrVADUnavailable, CodeVADUnavailable},
+	{ErrVADTimeout, CodeVADTimeout},
+}
+
+// ErrorCode 返回错误对应的错误码，未分类的错误为 internal
+func ErrorCode(err error) string {
+	for _, entry := range errorCodes {
+		if errors.Is(err, entry.kind) {
+			return entry.code
+		}
+	}
+	return CodeInternal
+}
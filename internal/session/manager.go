@@ -190,12 +190,12 @@ func (m *Manager) ProcessAudioData(sessionID string, audioData []byte) error {
 	session, exists := m.GetSession(sessionID)
 	if !exists {
 		logger.Errorf("Session %s not found when processing audio data", sessionID)
-		return fmt.Errorf("session %s not found", sessionID)
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
 	}
 
 	if atomic.LoadInt32(&session.closed) == 1 {
 		logger.Errorf("Session %s is closed, cannot process audio data", sessionID)
-		return fmt.Errorf("session %s is closed", sessionID)
+		return fmt.Errorf("%w: %s", ErrSessionClosed, sessionID)
 	}
 
 	// 检查并延迟分配VAD实例
@@ -203,7 +203,7 @@ func (m *Manager) ProcessAudioData(sessionID string, audioData []byte) error {
 		vadInstance, err := m.vadPool.Get()
 		if err != nil {
 			logger.Errorf("Failed to get VAD instance for session %s: %v", sessionID, err)
-			return fmt.Errorf("failed to get VAD instance for session %s: %v", sessionID, err)
+			return fmt.Errorf("%w: %v", ErrVADUnavailable, err)
 		}
 		session.VADInstance = vadInstance
 		logger.Infof("✅ Session %s assigned %s VAD instance %d", sessionID, vadInstance.GetType(), vadInstance.GetID())
@@ -216,12 +216,12 @@ func (m *Manager) ProcessAudioData(sessionID string, audioData []byte) error {
 	// 验证输入数据
 	if len(audioData) == 0 {
 		logger.Warnf("Session %s: Received empty audio data", sessionID)
-		return fmt.Errorf("empty audio data")
+		return fmt.Errorf("%w: empty", ErrInvalidAudio)
 	}
 
 	if len(audioData)%2 != 0 {
 		logger.Warnf("Session %s: Audio data length %d is not even (expecting 16-bit samples)", sessionID, len(audioData))
-		return fmt.Errorf("invalid audio data length: %d", len(audioData))
+		return fmt.Errorf("%w: length %d is not a multiple of 2", ErrInvalidAudio, len(audioData))
 	}
 
 	// 转换音频数据
@@ -283,7 +283,7 @@ func (m *Manager) processSileroVAD(session *Session, sessionID string, float32Sl
 		// VAD处理完成
 	case <-vadCtx.Done():
 		logger.Warnf("Session %s: VAD processing timeout", sessionID)
-		return fmt.Errorf("VAD processing timeout")
+		return ErrVADTimeout
 	}
 
 	// 处理语音段
@@ -301,7 +301,7 @@ func (m *Manager) processSileroVAD(session *Session, sessionID string, float32Sl
 			// 再次检查会话状态
 			if atomic.LoadInt32(&session.closed) == 1 {
 				logger.Warnf("Session %s closed during speech segment processing", sessionID)
-				return fmt.Errorf("session %s closed during processing", sessionID)
+				return fmt.Errorf("%w during processing: %s", ErrSessionClosed, sessionID)
 			}
 
 			// 验证音频数据
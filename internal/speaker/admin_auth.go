@@ -0,0 +1,47 @@
+package speaker
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminTokenHeader 管理接口令牌的请求头（也可使用 Authorization: Bearer <token>）
+const AdminTokenHeader = "X-Admin-Token"
+
+// AdminConfig 管理接口鉴权配置
+type AdminConfig struct {
+	Token string `json:"token"` // 管理接口令牌，为空时管理接口不可用
+}
+
+// adminAuth 管理接口鉴权：导出、导入、删除、校准等接口可读写全部租户的数据，必须携带配置的令牌
+func (h *Handler) adminAuth() gin.HandlerFunc {
+	token := h.manager.adminConfig.Token
+	return func(c *gin.Context) {
+		if token == "" {
+			respondError(c, newError(ErrNotEnabled, "admin api is disabled, set speaker.admin.token to enable it"))
+			c.Abort()
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(adminTokenFromRequest(c)), []byte(token)) != 1 {
+			respondError(c, newError(ErrUnauthorized, "invalid or missing admin token"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// adminTokenFromRequest 从请求中提取管理接口令牌
+// 优先级：请求头 X-Admin-Token > Authorization: Bearer
+func adminTokenFromRequest(c *gin.Context) string {
+	if token := c.GetHeader(AdminTokenHeader); token != "" {
+		return token
+	}
+	auth := c.GetHeader("Authorization")
+	if len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return auth[len("Bearer "):]
+	}
+	return ""
+}
@@ -0,0 +1,329 @@
+package speaker
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"voice_server/internal/logger"
+)
+
+const (
+	// archiveFormat 导出文件格式标识
+	archiveFormat = "voice_server.speaker_archive"
+	// archiveVersion 导出文件格式版本
+	archiveVersion = 1
+	// archivePreallocLimit 读取导出文件时最多预分配的样本点数量
+	archivePreallocLimit = 1024
+
+	// ImportModeMerge 合并：已存在的说话人追加样本（重新分配 sample_index）
+	ImportModeMerge = "merge"
+	// ImportModeReplace 替换：已存在的说话人先删除全部样本再导入
+	ImportModeReplace = "replace"
+)
+
+// ArchiveHeader 导出文件头（gzip 压缩的 JSON Lines，第一行为文件头，其后每行一个 StoredPoint）
+type ArchiveHeader struct {
+	Format       string    `json:"format"`
+	Version      int       `json:"version"`
+	Model        string    `json:"model"`
+	EmbeddingDim int       `json:"embedding_dim"`
+	Collection   string    `json:"collection"`
+	UID          string    `json:"uid,omitempty"`      // 为空表示导出整个 Collection
+	AgentID      string    `json:"agent_id,omitempty"` // 为空表示不按 agent_id 过滤
+	ExportedAt   time.Time `json:"exported_at"`
+	Count        int       `json:"count"`
+}
+
+// ImportOptions 导入参数
+type ImportOptions struct {
+	Mode               string `json:"mode"`                 // merge（默认）或 replace
+	DryRun             bool   `json:"dry_run"`              // 只校验和统计，不写入
+	AllowModelMismatch bool   `json:"allow_model_mismatch"` // 允许模型名称不一致（维度仍必须一致）
+}
+
+// ImportReport 导入结果
+type ImportReport struct {
+	Mode          string `json:"mode"`
+	DryRun        bool   `json:"dry_run"`
+	Model         string `json:"model"`
+	EmbeddingDim  int    `json:"embedding_dim"`
+	Speakers      int    `json:"speakers"`
+	Points        int    `json:"points"`
+	Created       int    `json:"created"`        // 新建的说话人数量
+	Merged        int    `json:"merged"`         // 追加样本的已有说话人数量
+	Replaced      int    `json:"replaced"`       // 被替换的已有说话人数量
+	SkippedPoints int    `json:"skipped_points"` // 缺少 uid 或 speaker_id 的样本点
+}
+
+// Export 导出说话人数据（uid、agentID 为空时导出整个 Collection）
+// beforeWrite 可选，在读取完样本点、开始写入 w 之前调用（HTTP 接口据此设置响应头）
+func (m *Manager) Export(w io.Writer, uid, agentID string, beforeWrite func(*ArchiveHeader)) (*ArchiveHeader, error) {
+	points, err := m.vectorDB.ExportPoints(uid, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vector database: %w", err)
+	}
+
+	header := &ArchiveHeader{
+		Format:       archiveFormat,
+		Version:      archiveVersion,
+		Model:        m.modelName,
+		EmbeddingDim: m.embeddingDim,
+		Collection:   m.vectorDB.collectionName,
+		UID:          uid,
+		AgentID:      agentID,
+		ExportedAt:   time.Now(),
+		Count:        len(points),
+	}
+	if beforeWrite != nil {
+		beforeWrite(header)
+	}
+
+	gz := gzip.NewWriter(w)
+	encoder := json.NewEncoder(gz)
+	if err := encoder.Encode(header); err != nil {
+		return nil, fmt.Errorf("failed to write archive header: %w", err)
+	}
+	for i := range points {
+		// 导出文件不包含 Point ID，记下原 ID，导入后模型迁移仍能据此找到注册音频
+		if _, ok := points[i].Payload["source_point_id"]; !ok {
+			points[i].Payload["source_point_id"] = points[i].ID
+		}
+		if err := encoder.Encode(&points[i]); err != nil {
+			return nil, fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	logger.Infof("Exported %d points (uid=%s, agent_id=%s, model=%s)", len(points), uid, agentID, m.modelName)
+	return header, nil
+}
+
+// Import 导入说话人数据
+// 导入前校验 embedding 维度和模型名称；Point ID 重新生成，不沿用导出环境的 ID
+func (m *Manager) Import(r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	if opts.Mode == "" {
+		opts.Mode = ImportModeMerge
+	}
+	if opts.Mode != ImportModeMerge && opts.Mode != ImportModeReplace {
+		return nil, newError(ErrInvalidArgument, "invalid import mode: %s", opts.Mode)
+	}
+
+	header, points, err := readArchive(r)
+	if err != nil {
+		return nil, err
+	}
+	if header.EmbeddingDim != m.embeddingDim {
+		return nil, newError(ErrInvalidArgument, "invalid archive: embedding dimension mismatch: expected %d, got %d", m.embeddingDim, header.EmbeddingDim)
+	}
+	if header.Model != m.modelName && !opts.AllowModelMismatch {
+		return nil, newError(ErrInvalidArgument, "invalid archive: model mismatch: expected %s, got %s", m.modelName, header.Model)
+	}
+
+	report := &ImportReport{
+		Mode:         opts.Mode,
+		DryRun:       opts.DryRun,
+		Model:        header.Model,
+		EmbeddingDim: header.EmbeddingDim,
+	}
+
+	// 按 uid:agent_id:speaker_id 分组
+	type speakerKey struct{ uid, agentID, speakerID string }
+	groups := make(map[speakerKey][]StoredPoint)
+	for _, point := range points {
+		if len(point.Vector) != m.embeddingDim {
+			return nil, newError(ErrInvalidArgument, "invalid archive: point vector dimension mismatch: expected %d, got %d", m.embeddingDim, len(point.Vector))
+		}
+		key := speakerKey{
+			uid:       payloadString(point.Payload, "uid"),
+			agentID:   payloadString(point.Payload, "agent_id"),
+			speakerID: payloadString(point.Payload, "speaker_id"),
+		}
+		if key.uid == "" || key.speakerID == "" {
+			report.SkippedPoints++
+			continue
+		}
+		groups[key] = append(groups[key], point)
+	}
+
+	keys := make([]speakerKey, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.uid != b.uid {
+			return a.uid < b.uid
+		}
+		if a.agentID != b.agentID {
+			return a.agentID < b.agentID
+		}
+		return a.speakerID < b.speakerID
+	})
+
+	for _, key := range keys {
+		group := groups[key]
+		sort.SliceStable(group, func(i, j int) bool {
+			return payloadInt(group[i].Payload, "sample_index") < payloadInt(group[j].Payload, "sample_index")
+		})
+
+		if err := m.importSpeaker(key.uid, key.agentID, key.speakerID, group, opts, report); err != nil {
+			return report, fmt.Errorf("failed to import speaker %s (uid=%s, agent_id=%s): %w", key.speakerID, key.uid, key.agentID, err)
+		}
+		report.Speakers++
+		report.Points += len(group)
+	}
+
+	logger.Infof("Imported archive: mode=%s, dry_run=%v, speakers=%d, points=%d, created=%d, merged=%d, replaced=%d",
+		report.Mode, report.DryRun, report.Speakers, report.Points, report.Created, report.Merged, report.Replaced)
+	return report, nil
+}
+
+// importSpeaker 导入单个说话人的样本
+func (m *Manager) importSpeaker(uid, agentID, speakerID string, group []StoredPoint, opts ImportOptions, report *ImportReport) error {
+	unlock := m.lockSpeaker(uid, speakerID)
+	defer unlock()
+
+	existing, err := m.vectorDB.ListSpeakerSamples(uid, agentID, speakerID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(existing) == 0:
+		report.Created++
+	case opts.Mode == ImportModeReplace:
+		report.Replaced++
+		if !opts.DryRun {
+			if err := m.vectorDB.DeleteSpeaker(uid, agentID, speakerID); err != nil {
+				return err
+			}
+		}
+	default:
+		report.Merged++
+	}
+
+	// 合并模式下在已有样本之后重新分配 sample_index，避免与已有样本冲突
+	next := -1
+	if len(existing) > 0 && opts.Mode == ImportModeMerge {
+		next = existing[len(existing)-1].SampleIndex + 1
+	}
+	for i := range group {
+		payload := make(map[string]any, len(group[i].Payload))
+		for k, v := range group[i].Payload {
+			payload[k] = v
+		}
+		if next >= 0 {
+			payload["sample_index"] = int64(next + i)
+		}
+		// 已通过模型校验，按当前模型标记，避免启动时的模型检查拒绝这些数据
+		payload["model"] = m.modelName
+		group[i].Payload = payload
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+	return m.vectorDB.InsertPoints(group)
+}
+
+// readArchive 读取并校验导出文件
+func readArchive(r io.Reader) (*ArchiveHeader, []StoredPoint, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, newError(ErrInvalidArgument, "invalid archive: %v", err)
+	}
+	defer gz.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(gz))
+	decoder.UseNumber()
+
+	var header ArchiveHeader
+	if err := decoder.Decode(&header); err != nil {
+		return nil, nil, newError(ErrInvalidArgument, "invalid archive: failed to read header: %v", err)
+	}
+	if header.Format != archiveFormat {
+		return nil, nil, newError(ErrInvalidArgument, "invalid archive: unknown format %q", header.Format)
+	}
+	if header.Version > archiveVersion {
+		return nil, nil, newError(ErrInvalidArgument, "invalid archive: unsupported version %d", header.Version)
+	}
+
+	if header.Count < 0 {
+		return nil, nil, newError(ErrInvalidArgument, "invalid archive: negative point count %d", header.Count)
+	}
+
+	// count 来自上传的文件，只作为预分配的参考，不按它分配内存
+	points := make([]StoredPoint, 0, min(header.Count, archivePreallocLimit))
+	for {
+		var point StoredPoint
+		if err := decoder.Decode(&point); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, newError(ErrInvalidArgument, "invalid archive: %v", err)
+		}
+		for key, value := range point.Payload {
+			point.Payload[key] = normalizeJSONValue(value)
+		}
+		points = append(points, point)
+		if len(points) > header.Count {
+			return nil, nil, newError(ErrInvalidArgument, "invalid archive: more points than the declared count %d", header.Count)
+		}
+	}
+	if len(points) != header.Count {
+		return nil, nil, newError(ErrInvalidArgument, "invalid archive: expected %d points, got %d", header.Count, len(points))
+	}
+
+	return &header, points, nil
+}
+
+// normalizeJSONValue 将 json.Number 还原为 int64 或 float64，保证 sample_index、created_at 等整数字段仍以整数写入
+func normalizeJSONValue(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if !strings.ContainsAny(v.String(), ".eE") {
+			if i, err := v.Int64(); err == nil {
+				return i
+			}
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeJSONValue(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeJSONValue(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// payloadString 读取 payload 中的字符串字段
+func payloadString(payload map[string]any, key string) string {
+	s, _ := payload[key].(string)
+	return s
+}
+
+// payloadInt 读取 payload 中的整数字段
+func payloadInt(payload map[string]any, key string) int64 {
+	switch v := payload[key].(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
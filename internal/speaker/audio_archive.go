@@ -0,0 +1,479 @@
+package speaker
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"voice_server/config"
+	"voice_server/internal/logger"
+
+	"github.com/go-audio/audio"
+	"github.com/go-audio/wav"
+)
+
+const (
+	// AudioKindRegister 注册/追加样本的音频
+	AudioKindRegister = "register"
+	// AudioKindIdentify 流式识别轮次的音频
+	AudioKindIdentify = "identify"
+
+	// archiveTenantsDir 归档根目录下按租户分区的子目录，清理器只处理该目录下的文件
+	archiveTenantsDir = "tenants"
+	// archiveDefaultAgent 没有 agent_id 时使用的目录名（编码后的目录名不含下划线，不会重名）
+	archiveDefaultAgent = "_default"
+	// archiveAnonymousUID 没有 uid 时使用的目录名
+	archiveAnonymousUID = "_anonymous"
+)
+
+// AudioArchiveConfig 音频归档保留策略
+type AudioArchiveConfig struct {
+	MaxAgeHours          int `json:"max_age_hours"`          // 超过该时长的音频会被删除，0 表示不限制（样本点仍存在的注册音频不删除）
+	MaxTotalMB           int `json:"max_total_mb"`           // 归档总大小上限，超出时从最旧的开始删除，0 表示不限制（样本点仍存在的注册音频不计入）
+	SweepIntervalMinutes int `json:"sweep_interval_minutes"` // 清理间隔，默认 60 分钟
+}
+
+// AudioRecord 归档音频的元数据（与音频同名的 .json 旁路文件）
+type AudioRecord struct {
+	File        string    `json:"file"` // 相对于归档根目录的路径
+	Kind        string    `json:"kind"`
+	UID         string    `json:"uid"`
+	AgentID     string    `json:"agent_id,omitempty"`
+	SpeakerID   string    `json:"speaker_id,omitempty"`
+	SpeakerUUID string    `json:"speaker_uuid,omitempty"`
+	SampleIndex *int      `json:"sample_index,omitempty"` // 注册音频对应的样本序号
+	PointID     string    `json:"point_id,omitempty"`     // 注册音频对应的样本点
+	RoundID     string    `json:"round_id,omitempty"`     // 识别轮次 ID
+	Round       int       `json:"round,omitempty"`        // 连接内的识别轮次序号
+	Identified  *bool     `json:"identified,omitempty"`   // 识别轮次的判定结果
+	Confidence  *float32  `json:"confidence,omitempty"`
+	SampleRate  int       `json:"sample_rate"`
+	Duration    float64   `json:"duration"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AudioArchive 按租户分区保存音频，并按保留策略定期清理
+// 目录结构：<root>/tenants/<hex(uid)>/<hex(agent_id)>/<kind>/<YYYYMMDD>/<kind>_<时间戳>_<point_id|round_id>.wav
+// uid 和 agent_id 由客户端提供，十六进制编码后作为目录名，避免 ".."、路径分隔符以及清理字符后不同租户重名
+type AudioArchive struct {
+	root   string
+	config AudioArchiveConfig
+
+	// livePoints 返回仍存在的样本点（清理时保留这些样本点的注册音频，模型迁移需要用它们重新提取），为空时不保留
+	livePoints func(pointIDs []string) (map[string]bool, error)
+
+	mu   sync.Mutex // 保存、清理、删除租户数据互斥
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewAudioArchive 创建音频归档
+func NewAudioArchive(root string, cfg AudioArchiveConfig, livePoints func([]string) (map[string]bool, error)) *AudioArchive {
+	if cfg.SweepIntervalMinutes <= 0 {
+		cfg.SweepIntervalMinutes = 60
+	}
+	return &AudioArchive{root: root, config: cfg, livePoints: livePoints}
+}
+
+// Root 返回归档根目录
+func (a *AudioArchive) Root() string {
+	return a.root
+}
+
+// archiveDirName 租户目录名：十六进制编码，为空时使用 empty
+func archiveDirName(s, empty string) string {
+	if s == "" {
+		return empty
+	}
+	return hex.EncodeToString([]byte(s))
+}
+
+// tenantDir 返回租户目录（agentID 为空时返回整个 uid 的目录）
+func (a *AudioArchive) tenantDir(uid, agentID string) (string, error) {
+	dir := filepath.Join(a.root, archiveTenantsDir, archiveDirName(uid, ""))
+	if agentID != "" {
+		dir = filepath.Join(dir, archiveDirName(agentID, ""))
+	}
+	return dir, a.checkTenantDir(dir)
+}
+
+// checkTenantDir 确认目录位于租户分区之内（且不是分区本身），删除和写入前调用
+func (a *AudioArchive) checkTenantDir(dir string) error {
+	base := filepath.Clean(filepath.Join(a.root, archiveTenantsDir))
+	dir = filepath.Clean(dir)
+	if dir == base || !isWithinDir(base, dir) {
+		return fmt.Errorf("audio archive path %s is outside %s", dir, base)
+	}
+	return nil
+}
+
+// Save 保存音频和元数据
+// 注册音频文件名以 Point ID 结尾，模型迁移时据此找回样本对应的注册音频
+func (a *AudioArchive) Save(record *AudioRecord, audioData []float32, sampleRate int) error {
+	if len(audioData) == 0 {
+		return fmt.Errorf("audio data is empty")
+	}
+
+	now := time.Now()
+	// 流式识别允许不指定 uid
+	dir := filepath.Join(a.root, archiveTenantsDir,
+		archiveDirName(record.UID, archiveAnonymousUID), archiveDirName(record.AgentID, archiveDefaultAgent),
+		record.Kind, now.Format("20060102"))
+	if err := a.checkTenantDir(dir); err != nil {
+		return err
+	}
+
+	suffix := record.PointID
+	if record.Kind == AudioKindIdentify {
+		suffix = record.RoundID
+	}
+	name := fmt.Sprintf("%s_%s", record.Kind, now.Format("20060102_150405"))
+	if suffix != "" {
+		name += "_" + sanitizeFileComponent(suffix)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	wavPath := filepath.Join(dir, name+".wav")
+	size, err := writeWAVFile(wavPath, audioData, sampleRate)
+	if err != nil {
+		return err
+	}
+
+	record.File, _ = filepath.Rel(a.root, wavPath)
+	record.SampleRate = sampleRate
+	record.Duration = float64(len(audioData)) / float64(sampleRate)
+	record.Size = size
+	record.CreatedAt = now
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode audio metadata: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".json"), data, 0644); err != nil {
+		os.Remove(wavPath)
+		return fmt.Errorf("failed to write audio metadata: %w", err)
+	}
+
+	logger.Debugf("Archived %s audio: %s, samples: %d, duration: %.2fs", record.Kind, wavPath, len(audioData), record.Duration)
+	return nil
+}
+
+// Start 启动后台清理（未配置保留策略时不启动）
+func (a *AudioArchive) Start() {
+	if a.config.MaxAgeHours <= 0 && a.config.MaxTotalMB <= 0 {
+		return
+	}
+
+	a.stop = make(chan struct{})
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ticker := time.NewTicker(time.Duration(a.config.SweepIntervalMinutes) * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			if files, bytes, err := a.Sweep(); err != nil {
+				logger.Warnf("Audio archive sweep failed: %v", err)
+			} else if files > 0 {
+				logger.Infof("Audio archive sweep removed %d files (%d bytes)", files, bytes)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-a.stop:
+				return
+			}
+		}
+	}()
+	logger.Infof("✅ Audio archive sweeper started: root=%s, max_age_hours=%d, max_total_mb=%d",
+		a.root, a.config.MaxAgeHours, a.config.MaxTotalMB)
+}
+
+// Stop 停止后台清理
+func (a *AudioArchive) Stop() {
+	if a.stop == nil {
+		return
+	}
+	close(a.stop)
+	a.wg.Wait()
+	a.stop = nil
+}
+
+// archivedFile 清理时扫描到的归档文件
+type archivedFile struct {
+	path      string
+	size      int64
+	createdAt time.Time
+	pointID   string // 注册音频对应的样本点
+}
+
+// Sweep 按保留策略清理：先删除超龄的音频，再从最旧的开始删除直到总大小不超过上限
+// 样本点仍存在的注册音频不清理，也不计入总大小；无法确认样本点是否存在时本次保留全部注册音频
+func (a *AudioArchive) Sweep() (int, int64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	base := filepath.Join(a.root, archiveTenantsDir)
+	if _, err := os.Stat(base); os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+
+	files := make([]archivedFile, 0)
+	err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".wav") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		file := archivedFile{path: path, size: info.Size(), createdAt: info.ModTime()}
+		// 优先使用元数据中的创建时间
+		if data, err := os.ReadFile(strings.TrimSuffix(path, ".wav") + ".json"); err == nil {
+			var record AudioRecord
+			if json.Unmarshal(data, &record) == nil {
+				if !record.CreatedAt.IsZero() {
+					file.createdAt = record.CreatedAt
+				}
+				if record.Kind == AudioKindRegister {
+					file.pointID = record.PointID
+				}
+			}
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to scan audio archive: %w", err)
+	}
+
+	files = a.excludeLiveRegisterAudio(files)
+
+	sort.Slice(files, func(i, j int) bool { return files[i].createdAt.Before(files[j].createdAt) })
+
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+
+	removed := 0
+	var removedBytes int64
+	cutoff := time.Now().Add(-time.Duration(a.config.MaxAgeHours) * time.Hour)
+	maxBytes := int64(a.config.MaxTotalMB) * 1024 * 1024
+	for _, file := range files {
+		expired := a.config.MaxAgeHours > 0 && file.createdAt.Before(cutoff)
+		oversize := maxBytes > 0 && total > maxBytes
+		if !expired && !oversize {
+			break // 已按创建时间排序，后面的文件更新
+		}
+		if err := removeArchivedAudio(file.path); err != nil {
+			logger.Warnf("Failed to remove archived audio %s: %v", file.path, err)
+			continue
+		}
+		total -= file.size
+		removed++
+		removedBytes += file.size
+	}
+
+	return removed, removedBytes, nil
+}
+
+// excludeLiveRegisterAudio 去掉样本点仍存在的注册音频
+func (a *AudioArchive) excludeLiveRegisterAudio(files []archivedFile) []archivedFile {
+	if a.livePoints == nil {
+		return files
+	}
+
+	pointIDs := make([]string, 0)
+	for _, file := range files {
+		if file.pointID != "" {
+			pointIDs = append(pointIDs, file.pointID)
+		}
+	}
+	if len(pointIDs) == 0 {
+		return files
+	}
+
+	live, err := a.livePoints(pointIDs)
+	if err != nil {
+		logger.Warnf("Failed to check points of archived register audio, keeping all of it: %v", err)
+	}
+
+	kept := files[:0]
+	for _, file := range files {
+		if file.pointID != "" && (err != nil || live[file.pointID]) {
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept
+}
+
+// EraseTenant 删除 uid（可选 agentID）的全部归档音频，返回删除的文件（相对路径）和字节数
+func (a *AudioArchive) EraseTenant(uid, agentID string) ([]string, int64, error) {
+	if uid == "" {
+		return nil, 0, newError(ErrInvalidArgument, "uid is required")
+	}
+
+	dir, err := a.tenantDir(uid, agentID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+
+	files := make([]string, 0)
+	var bytes int64
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".wav") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			bytes += info.Size()
+		}
+		rel, _ := filepath.Rel(a.root, path)
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to scan audio archive: %w", err)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, 0, fmt.Errorf("failed to delete audio archive: %w", err)
+	}
+	return files, bytes, nil
+}
+
+// removeArchivedAudio 删除音频及其元数据，并尝试删除空的日期目录
+func removeArchivedAudio(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	os.Remove(strings.TrimSuffix(path, ".wav") + ".json")
+	os.Remove(filepath.Dir(path)) // 目录非空时删除失败，忽略
+	return nil
+}
+
+// writeWAVFile 将音频数据保存为 16 位单声道 WAV 文件，返回文件大小
+func writeWAVFile(path string, audioData []float32, sampleRate int) (int64, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	// 将 float32 转换为 int16
+	// float32 范围是 [-1.0, 1.0]，需要转换为 int16 范围 [-32768, 32767]
+	int16Data := make([]int, len(audioData))
+	normalizeFactor := config.GlobalConfig.Audio.NormalizeFactor
+	for i, sample := range audioData {
+		// 限制范围到 [-1.0, 1.0]
+		if sample > 1.0 {
+			sample = 1.0
+		} else if sample < -1.0 {
+			sample = -1.0
+		}
+		int16Data[i] = int(sample * normalizeFactor)
+	}
+
+	format := &audio.Format{
+		NumChannels: 1, // 单声道
+		SampleRate:  sampleRate,
+	}
+	encoder := wav.NewEncoder(file, format.SampleRate, 16, format.NumChannels, 1)
+	buf := &audio.IntBuffer{
+		Format:         format,
+		SourceBitDepth: 16,
+		Data:           int16Data,
+	}
+	if err := encoder.Write(buf); err != nil {
+		return 0, fmt.Errorf("failed to write audio data: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return 0, fmt.Errorf("failed to close encoder: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, nil
+	}
+	return info.Size(), nil
+}
+
+// List 读取 uid（可选 agentID）的归档音频元数据，按创建时间排序
+func (a *AudioArchive) List(uid, agentID string) ([]*AudioRecord, error) {
+	if uid == "" {
+		return nil, newError(ErrInvalidArgument, "uid is required")
+	}
+
+	dir, err := a.tenantDir(uid, agentID)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*AudioRecord, 0)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return records, nil
+	}
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil // 可能正在被清理
+		}
+		var record AudioRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			logger.Warnf("Invalid audio metadata %s: %v", path, err)
+			return nil
+		}
+		records = append(records, &record)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan audio archive: %w", err)
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].CreatedAt.Before(records[j].CreatedAt) })
+	return records, nil
+}
+
+// ArchiveAudio 归档注册或识别音频
+func (m *Manager) ArchiveAudio(record *AudioRecord, audioData []float32, sampleRate int) error {
+	return m.audioArchive.Save(record, audioData, sampleRate)
+}
+
+// ListArchivedAudio 读取归档音频元数据
+func (m *Manager) ListArchivedAudio(uid, agentID string) ([]*AudioRecord, error) {
+	return m.audioArchive.List(uid, agentID)
+}
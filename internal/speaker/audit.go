@@ -0,0 +1,472 @@
+package speaker
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"voice_server/internal/logger"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// AuditActionRegister 注册声纹
+	AuditActionRegister = "register"
+	// AuditActionRegisterWS 流式注册的一个轮次
+	AuditActionRegisterWS = "register_ws"
+	// AuditActionAddSample 追加样本
+	AuditActionAddSample = "add_sample"
+	// AuditActionIdentify 识别声纹（HTTP）
+	AuditActionIdentify = "identify"
+	// AuditActionIdentifyWS 流式识别的一个轮次
+	AuditActionIdentifyWS = "identify_ws"
+	// AuditActionVerify 验证声纹
+	AuditActionVerify = "verify"
+	// AuditActionVerifyChallenge 挑战-应答验证
+	AuditActionVerifyChallenge = "verify_challenge"
+	// AuditActionDelete 删除说话人或单个 UUID 的样本
+	AuditActionDelete = "delete"
+	// AuditActionDeleteSample 按序号删除单个样本
+	AuditActionDeleteSample = "delete_sample"
+	// AuditActionRename 修改说话人名称
+	AuditActionRename = "rename"
+	// AuditActionMerge 合并说话人
+	AuditActionMerge = "merge"
+	// AuditActionSplit 拆分说话人
+	AuditActionSplit = "split"
+	// AuditActionErase 删除 uid 的全部数据
+	AuditActionErase = "erase"
+
+	// AuditDecisionAccept 识别/验证通过
+	AuditDecisionAccept = "accept"
+	// AuditDecisionReject 识别/验证未通过
+	AuditDecisionReject = "reject"
+	// AuditDecisionSuccess 写操作成功
+	AuditDecisionSuccess = "success"
+	// AuditDecisionError 请求失败（参数错误、未找到、内部错误等）
+	AuditDecisionError = "error"
+
+	// auditFileName 审计日志文件名（轮转后的备份为 audit-<时间>.jsonl[.gz]）
+	auditFileName = "audit.jsonl"
+)
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	Enabled bool               `json:"enabled"`
+	File    AuditFileConfig    `json:"file"`
+	Webhook AuditWebhookConfig `json:"webhook"`
+}
+
+// AuditFileConfig JSONL 文件输出（查询接口读取该文件）
+type AuditFileConfig struct {
+	Enabled    bool   `json:"enabled"`
+	Dir        string `json:"dir"`          // 为空时使用 <data_dir>/audit
+	MaxSizeMB  int    `json:"max_size_mb"`  // 单个文件大小上限，默认 100
+	MaxBackups int    `json:"max_backups"`  // 保留的轮转文件数，0 表示不限制
+	MaxAgeDays int    `json:"max_age_days"` // 轮转文件保留天数，0 表示不限制
+	Compress   bool   `json:"compress"`     // 是否 gzip 压缩轮转文件
+}
+
+// AuditWebhookConfig HTTP webhook 输出（逐条 POST JSON）
+type AuditWebhookConfig struct {
+	Enabled   bool              `json:"enabled"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers"`    // 附加请求头（如鉴权）
+	TimeoutMs int               `json:"timeout_ms"` // 单次请求超时，默认 5000
+	QueueSize int               `json:"queue_size"` // 发送队列长度，队列满时丢弃并记录警告，默认 1000
+}
+
+// AuditEntry 一条审计记录
+type AuditEntry struct {
+	Time      time.Time              `json:"time"`
+	Action    string                 `json:"action"`
+	Decision  string                 `json:"decision"`
+	UID       string                 `json:"uid,omitempty"`
+	AgentID   string                 `json:"agent_id,omitempty"`
+	SpeakerID string                 `json:"speaker_id,omitempty"`
+	Score     *float32               `json:"score,omitempty"` // 识别/验证的判定分数（启用分数归一化时为归一化分数）
+	Threshold *float32               `json:"threshold,omitempty"`
+	ClientIP  string                 `json:"client_ip"`
+	RequestID string                 `json:"request_id"`
+	RoundID   string                 `json:"round_id,omitempty"` // 流式识别轮次 ID
+	Round     int                    `json:"round,omitempty"`
+	Status    int                    `json:"status,omitempty"` // HTTP 状态码
+	Error     string                 `json:"error,omitempty"`
+	Detail    map[string]interface{} `json:"detail,omitempty"`
+}
+
+// AuditSink 审计记录的输出目标
+type AuditSink interface {
+	Write(entry *AuditEntry) error
+	Close() error
+}
+
+// AuditFilter 审计记录查询条件（空字段不过滤）
+type AuditFilter struct {
+	UID       string
+	AgentID   string
+	SpeakerID string
+	Action    string
+	Decision  string
+	RequestID string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+// AuditLog 将审计记录写入所有输出目标
+type AuditLog struct {
+	sinks []AuditSink
+	file  *FileAuditSink // 查询接口使用，未启用文件输出时为空
+}
+
+// NewAuditLog 根据配置创建审计日志，未启用时返回 nil
+func NewAuditLog(cfg AuditConfig, dataDir string) (*AuditLog, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	auditLog := &AuditLog{}
+	if cfg.File.Enabled {
+		dir := cfg.File.Dir
+		if dir == "" {
+			if dataDir == "" {
+				dataDir = "data/speaker"
+			}
+			dir = filepath.Join(dataDir, "audit")
+		}
+		fileSink, err := NewFileAuditSink(dir, cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		auditLog.file = fileSink
+		auditLog.sinks = append(auditLog.sinks, fileSink)
+	}
+	if cfg.Webhook.Enabled {
+		if cfg.Webhook.URL == "" {
+			auditLog.Close()
+			return nil, fmt.Errorf("audit webhook url is required")
+		}
+		auditLog.sinks = append(auditLog.sinks, NewWebhookAuditSink(cfg.Webhook))
+	}
+	if len(auditLog.sinks) == 0 {
+		logger.Warnf("Audit log enabled but no sink is enabled")
+	}
+	return auditLog, nil
+}
+
+// Record 写入一条审计记录，写入失败只记录警告，不影响请求本身
+func (a *AuditLog) Record(entry *AuditEntry) {
+	if a == nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	for _, sink := range a.sinks {
+		if err := sink.Write(entry); err != nil {
+			logger.Warnf("Failed to write audit entry (action=%s, request_id=%s): %v", entry.Action, entry.RequestID, err)
+		}
+	}
+}
+
+// Query 按条件查询审计记录，按时间倒序返回
+func (a *AuditLog) Query(filter AuditFilter) ([]*AuditEntry, error) {
+	if a == nil || a.file == nil {
+		return nil, newError(ErrNotEnabled, "audit file sink is not enabled")
+	}
+	return a.file.Query(filter)
+}
+
+// Close 关闭所有输出目标
+func (a *AuditLog) Close() {
+	if a == nil {
+		return
+	}
+	for _, sink := range a.sinks {
+		if err := sink.Close(); err != nil {
+			logger.Warnf("Failed to close audit sink: %v", err)
+		}
+	}
+}
+
+// FileAuditSink 追加写入 JSONL 文件，按大小轮转
+type FileAuditSink struct {
+	dir    string
+	mu     sync.Mutex
+	writer *lumberjack.Logger
+}
+
+// NewFileAuditSink 创建文件输出
+func NewFileAuditSink(dir string, cfg AuditFileConfig) (*FileAuditSink, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	if cfg.MaxSizeMB <= 0 {
+		cfg.MaxSizeMB = 100
+	}
+	return &FileAuditSink{
+		dir: dir,
+		writer: &lumberjack.Logger{
+			Filename:   filepath.Join(dir, auditFileName),
+			MaxSize:    cfg.MaxSizeMB,
+			MaxBackups: cfg.MaxBackups,
+			MaxAge:     cfg.MaxAgeDays,
+			Compress:   cfg.Compress,
+			LocalTime:  true,
+		},
+	}, nil
+}
+
+// Write 追加一行
+func (s *FileAuditSink) Write(entry *AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit file: %w", err)
+	}
+	return nil
+}
+
+// Close 关闭文件
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writer.Close()
+}
+
+// Query 从新到旧扫描当前文件和轮转文件，取满 Limit 条后不再读取更旧的文件
+func (s *FileAuditSink) Query(filter AuditFilter) ([]*AuditEntry, error) {
+	files, err := s.auditFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// 不持有写入锁：读取期间轮转的文件仍可通过已打开的句柄读完，当前文件末尾写到一半的行会被跳过
+	entries := make([]*AuditEntry, 0)
+	for _, name := range files {
+		matched, err := readAuditFile(filepath.Join(s.dir, name), filter)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, matched...)
+		// 文件从新到旧读取，更旧的文件中的记录不会进入结果
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			break
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Time.After(entries[j].Time) })
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+// auditFiles 列出审计文件，从新到旧排列：当前文件在前，轮转的备份按文件名中的时间戳倒序
+func (s *FileAuditSink) auditFiles() ([]string, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit directory: %w", err)
+	}
+
+	present := make(map[string]bool, len(dirEntries))
+	for _, entry := range dirEntries {
+		present[entry.Name()] = true
+	}
+
+	backups := make([]string, 0, len(dirEntries))
+	current := false
+	for _, entry := range dirEntries {
+		name := entry.Name()
+		switch {
+		case entry.IsDir() || !strings.HasPrefix(name, "audit"):
+		case name == auditFileName:
+			current = true
+		case strings.HasSuffix(name, ".jsonl"):
+			backups = append(backups, name)
+		case strings.HasSuffix(name, ".jsonl.gz"):
+			// 正在压缩的备份，未压缩的文件还在时只读一份
+			if !present[strings.TrimSuffix(name, ".gz")] {
+				backups = append(backups, name)
+			}
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	if current {
+		backups = append([]string{auditFileName}, backups...)
+	}
+	return backups, nil
+}
+
+// readAuditFile 读取单个审计文件中符合条件的记录（指定 Limit 时只保留文件中最新的 Limit 条）
+func readAuditFile(path string, filter AuditFilter) ([]*AuditEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// 扫描目录后被轮转清理
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit file %s: %w", filepath.Base(path), err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	var entries []*AuditEntry
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// 进程崩溃时可能留下半行，跳过
+			continue
+		}
+		if !filter.match(&entry) {
+			continue
+		}
+		entries = append(entries, &entry)
+		// 文件内按时间顺序写入，超出 Limit 较多时丢弃较早的记录，避免读取大文件时占用过多内存
+		if filter.Limit > 0 && len(entries) >= 2*filter.Limit {
+			entries = append(entries[:0], entries[len(entries)-filter.Limit:]...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit file %s: %w", filepath.Base(path), err)
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+// match 判断记录是否符合查询条件
+func (f AuditFilter) match(entry *AuditEntry) bool {
+	switch {
+	case f.UID != "" && entry.UID != f.UID,
+		f.AgentID != "" && entry.AgentID != f.AgentID,
+		f.SpeakerID != "" && entry.SpeakerID != f.SpeakerID,
+		f.Action != "" && entry.Action != f.Action,
+		f.Decision != "" && entry.Decision != f.Decision,
+		f.RequestID != "" && entry.RequestID != f.RequestID,
+		!f.Since.IsZero() && entry.Time.Before(f.Since),
+		!f.Until.IsZero() && entry.Time.After(f.Until):
+		return false
+	}
+	return true
+}
+
+// WebhookAuditSink 异步逐条 POST 到 webhook，失败只记录警告
+type WebhookAuditSink struct {
+	config AuditWebhookConfig
+	client *http.Client
+	queue  chan []byte
+	wg     sync.WaitGroup
+}
+
+// NewWebhookAuditSink 创建 webhook 输出并启动发送协程
+func NewWebhookAuditSink(cfg AuditWebhookConfig) *WebhookAuditSink {
+	if cfg.TimeoutMs <= 0 {
+		cfg.TimeoutMs = 5000
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	sink := &WebhookAuditSink{
+		config: cfg,
+		client: &http.Client{Timeout: time.Duration(cfg.TimeoutMs) * time.Millisecond},
+		queue:  make(chan []byte, cfg.QueueSize),
+	}
+	sink.wg.Add(1)
+	go sink.run()
+	return sink
+}
+
+// Write 放入发送队列，队列满时丢弃
+func (s *WebhookAuditSink) Write(entry *AuditEntry) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	select {
+	case s.queue <- body:
+		return nil
+	default:
+		return fmt.Errorf("audit webhook queue is full, entry dropped")
+	}
+}
+
+// Close 发送完队列中剩余的记录后返回
+func (s *WebhookAuditSink) Close() error {
+	close(s.queue)
+	s.wg.Wait()
+	return nil
+}
+
+// run 发送协程
+func (s *WebhookAuditSink) run() {
+	defer s.wg.Done()
+	for body := range s.queue {
+		if err := s.post(body); err != nil {
+			logger.Warnf("Failed to deliver audit entry to webhook: %v", err)
+		}
+	}
+}
+
+// post 发送一条记录
+func (s *WebhookAuditSink) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// RecordAudit 写入审计记录（未启用审计时忽略）
+func (m *Manager) RecordAudit(entry *AuditEntry) {
+	m.auditLog.Record(entry)
+}
+
+// QueryAudit 查询审计记录
+func (m *Manager) QueryAudit(filter AuditFilter) ([]*AuditEntry, error) {
+	return m.auditLog.Query(filter)
+}
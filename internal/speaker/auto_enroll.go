@@ -0,0 +1,113 @@
+package speaker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"voice_server/internal/logger"
+)
+
+// AnonymousSpeakerPrefix 自动注册的匿名说话人 ID 前缀
+const AnonymousSpeakerPrefix = "anon-"
+
+// AutoEnrollConfig 未识别说话人的自动注册配置（默认关闭）
+type AutoEnrollConfig struct {
+	Enabled    bool `json:"enabled"`
+	MaxSamples int  `json:"max_samples"` // 每个匿名说话人最多累积的样本数，达到后只识别不再追加，默认 5
+}
+
+// AutoEnrollment 自动注册结果（附在识别结果中）
+type AutoEnrollment struct {
+	SpeakerID   string `json:"speaker_id"`
+	Created     bool   `json:"created"`                // true 表示新建了匿名说话人，false 表示追加到已有的匿名说话人
+	SampleIndex *int   `json:"sample_index,omitempty"` // 写入的样本序号，匿名说话人样本已满时为空
+	PointID     string `json:"point_id,omitempty"`
+}
+
+// IsAnonymousSpeaker 判断是否为自动注册的匿名说话人
+func IsAnonymousSpeaker(speakerID string) bool {
+	return strings.HasPrefix(speakerID, AnonymousSpeakerPrefix)
+}
+
+// autoEnroll 识别未命中时为该声音新建匿名说话人；命中匿名说话人时追加样本（直到样本上限），
+// 同一个声音再次出现时会识别为同一个匿名 ID。只在同时指定 uid、agent_id 且没有 speaker_id/speaker_name 过滤时生效，
+// 净语音时长 speechDuration（秒）不足 streaming.min_speech_ms 时不注册。
+// 自动注册失败只记录日志，不影响识别结果
+func (m *Manager) autoEnroll(uid, agentID, speakerID, speakerName string, embedding []float32, speechDuration float64, result *IdentifyResult) {
+	cfg := m.autoEnrollConfig
+	if !cfg.Enabled || uid == "" || agentID == "" || speakerID != "" || speakerName != "" {
+		return
+	}
+	if minSpeech := float64(m.streamingConfig.MinSpeechMs) / 1000; minSpeech > 0 && speechDuration < minSpeech {
+		logger.Debugf("Skipping auto-enrollment for uid %s: %.2fs speech, at least %.2fs required", uid, speechDuration, minSpeech)
+		return
+	}
+
+	var (
+		enrollment *AutoEnrollment
+		err        error
+	)
+	switch {
+	case !result.Identified:
+		enrollment, err = m.createAnonymousSpeaker(uid, agentID, embedding)
+	case IsAnonymousSpeaker(result.SpeakerID):
+		enrollment, err = m.appendAnonymousSample(uid, agentID, result.SpeakerID, embedding, cfg.MaxSamples)
+	default:
+		return
+	}
+	if err != nil {
+		logger.Warnf("Auto-enrollment failed for uid %s, agent_id %s: %v", uid, agentID, err)
+		return
+	}
+	result.AutoEnrolled = enrollment
+}
+
+// createAnonymousSpeaker 新建匿名说话人，名称与 ID 相同，管理员可以之后改名或合并
+func (m *Manager) createAnonymousSpeaker(uid, agentID string, embedding []float32) (*AutoEnrollment, error) {
+	id, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+	speakerID := AnonymousSpeakerPrefix + id[:8]
+
+	unlock := m.lockSpeaker(uid, speakerID)
+	defer unlock()
+
+	now := time.Now().Unix()
+	pointID, err := m.vectorDB.Insert(uid, agentID, speakerID, speakerID, id, embedding, 0, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert to vector database: %w", err)
+	}
+
+	logger.Infof("Auto-enrolled anonymous speaker %s for uid %s, agent_id %s", speakerID, uid, agentID)
+	sampleIndex := 0
+	return &AutoEnrollment{SpeakerID: speakerID, Created: true, SampleIndex: &sampleIndex, PointID: pointID}, nil
+}
+
+// appendAnonymousSample 向匿名说话人追加样本，样本数达到上限后不再追加
+func (m *Manager) appendAnonymousSample(uid, agentID, speakerID string, embedding []float32, maxSamples int) (*AutoEnrollment, error) {
+	unlock := m.lockSpeaker(uid, speakerID)
+	defer unlock()
+
+	samples, err := m.vectorDB.ListSpeakerSamples(uid, agentID, speakerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list samples: %w", err)
+	}
+	enrollment := &AutoEnrollment{SpeakerID: speakerID}
+	if len(samples) == 0 || len(samples) >= maxSamples {
+		return enrollment, nil
+	}
+
+	latest := samples[len(samples)-1]
+	sampleIndex := nextSampleIndexOf(samples)
+	now := time.Now().Unix()
+	enrollment.PointID, err = m.vectorDB.Insert(uid, agentID, speakerID, latest.SpeakerName, latest.UUID, embedding, sampleIndex, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert to vector database: %w", err)
+	}
+	enrollment.SampleIndex = &sampleIndex
+
+	logger.Debugf("Auto-enrollment added sample %d to anonymous speaker %s for uid %s", sampleIndex, speakerID, uid)
+	return enrollment, nil
+}
@@ -0,0 +1,419 @@
+package speaker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"voice_server/internal/logger"
+)
+
+const (
+	// CalibrationCriterionEER 以等错误率（EER）对应的阈值作为推荐阈值
+	CalibrationCriterionEER = "eer"
+	// CalibrationCriterionMinDCF 以最小检测代价（minDCF）对应的阈值作为推荐阈值
+	CalibrationCriterionMinDCF = "mindcf"
+
+	// calibrationUID 校准时在临时 Collection 中注册使用的 UID
+	calibrationUID = "__calibration__"
+	// defaultCalibrationPoints 未指定候选阈值时在分数范围内均匀取的点数
+	defaultCalibrationPoints = 11
+)
+
+// CalibrationConfig 阈值校准配置
+type CalibrationConfig struct {
+	RootDir string `json:"root_dir"` // 管理接口可读取的标注数据根目录，为空时管理接口不可用（eval 子命令不受限制）
+}
+
+// ConfigWriter 配置写回接口（由配置热加载管理器实现）
+type ConfigWriter interface {
+	SetConfigValue(key string, value interface{}) error
+	SaveConfig() error
+}
+
+// CalibrationOptions 阈值校准参数
+type CalibrationOptions struct {
+	Dir              string    `json:"dir"`                // 标注数据目录：<dir>/<speaker_id>/*.wav
+	EnrollPerSpeaker int       `json:"enroll_per_speaker"` // 每个说话人用于注册的文件数，默认 1，其余用于测试
+	PTarget          float64   `json:"p_target"`           // DCF 目标先验概率，默认 0.01
+	CostMiss         float64   `json:"cost_miss"`          // DCF 漏检代价，默认 1
+	CostFA           float64   `json:"cost_fa"`            // DCF 误识代价，默认 1
+	Criterion        string    `json:"criterion"`          // 推荐阈值依据：eer 或 mindcf，默认 mindcf
+	Thresholds       []float32 `json:"thresholds"`         // 需要报告 FAR/FRR 的候选阈值（可选）
+}
+
+// CalibrationPoint 某个阈值下的误识率和拒识率
+type CalibrationPoint struct {
+	Threshold float32 `json:"threshold"`
+	FAR       float64 `json:"far"`
+	FRR       float64 `json:"frr"`
+}
+
+// CalibrationReport 阈值校准报告
+type CalibrationReport struct {
+	Speakers             int                `json:"speakers"`
+	EnrollFiles          int                `json:"enroll_files"`
+	TestFiles            int                `json:"test_files"`
+	TargetTrials         int                `json:"target_trials"`
+	NonTargetTrials      int                `json:"nontarget_trials"`
+	ScoreNormalization   string             `json:"score_normalization,omitempty"`
+	EER                  float64            `json:"eer"`
+	EERThreshold         float32            `json:"eer_threshold"`
+	MinDCF               float64            `json:"min_dcf"`
+	MinDCFThreshold      float32            `json:"min_dcf_threshold"`
+	PTarget              float64            `json:"p_target"`
+	Criterion            string             `json:"criterion"`
+	CurrentThreshold     float32            `json:"current_threshold"`
+	RecommendedThreshold float32            `json:"recommended_threshold"`
+	Points               []CalibrationPoint `json:"points"`
+	Skipped              []string           `json:"skipped,omitempty"`
+	Applied              bool               `json:"applied"`
+	AppliedKey           string             `json:"applied_key,omitempty"`
+}
+
+// EvaluateTrials 使用标注数据评估识别效果并推荐阈值
+// 每个说话人的前 EnrollPerSpeaker 个文件注册到临时 Collection 中，其余文件作为测试，
+// 与所有已注册说话人打分：同一说话人为目标试验，其他为非目标试验。评估结束后删除临时 Collection。
+func (m *Manager) EvaluateTrials(opts CalibrationOptions) (*CalibrationReport, error) {
+	if opts.Dir == "" {
+		return nil, newError(ErrInvalidArgument, "dir is required")
+	}
+	if opts.EnrollPerSpeaker <= 0 {
+		opts.EnrollPerSpeaker = 1
+	}
+	if opts.PTarget <= 0 || opts.PTarget >= 1 {
+		opts.PTarget = 0.01
+	}
+	if opts.CostMiss <= 0 {
+		opts.CostMiss = 1
+	}
+	if opts.CostFA <= 0 {
+		opts.CostFA = 1
+	}
+	opts.Criterion = strings.ToLower(opts.Criterion)
+	if opts.Criterion == "" {
+		opts.Criterion = CalibrationCriterionMinDCF
+	}
+	if opts.Criterion != CalibrationCriterionEER && opts.Criterion != CalibrationCriterionMinDCF {
+		return nil, newError(ErrInvalidArgument, "unsupported criterion: %s", opts.Criterion)
+	}
+
+	trials, err := listTrialFiles(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CalibrationReport{
+		ScoreNormalization: m.scoreNormMethod(),
+		PTarget:            opts.PTarget,
+		Criterion:          opts.Criterion,
+		CurrentThreshold:   m.defaultThreshold(),
+	}
+
+	// 注册到临时 Collection，不写入正式数据
+	scratch := m.vectorDB.withCollection(fmt.Sprintf("%s_calibration_%d", m.vectorDB.collectionName, time.Now().UnixNano()))
+	if err := scratch.ensureCollectionExists(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to create scratch collection: %w", err)
+	}
+	defer func() {
+		if err := scratch.dropCollection(); err != nil {
+			logger.Warnf("Calibration: failed to drop scratch collection %s: %v", scratch.collectionName, err)
+		}
+	}()
+
+	enrolled := make([]string, 0, len(trials))
+
+	speakerIDs := make([]string, 0, len(trials))
+	for speakerID := range trials {
+		speakerIDs = append(speakerIDs, speakerID)
+	}
+	sort.Strings(speakerIDs)
+
+	testFiles := make(map[string][]string)
+	for _, speakerID := range speakerIDs {
+		files := trials[speakerID]
+		if len(files) <= opts.EnrollPerSpeaker {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s: not enough files (%d)", speakerID, len(files)))
+			continue
+		}
+
+		registered := 0
+		for _, path := range files[:opts.EnrollPerSpeaker] {
+			embedding, err := m.embedTrialFile(path)
+			if err == nil {
+				now := time.Now().Unix()
+				_, err = scratch.Insert(calibrationUID, "calibration", speakerID, speakerID, speakerID, embedding, registered, now, now)
+			}
+			if err != nil {
+				report.Skipped = append(report.Skipped, fmt.Sprintf("%s: %v", path, err))
+				continue
+			}
+			registered++
+		}
+		if registered == 0 {
+			continue
+		}
+
+		enrolled = append(enrolled, speakerID)
+		report.EnrollFiles += registered
+		testFiles[speakerID] = files[opts.EnrollPerSpeaker:]
+	}
+	report.Speakers = len(enrolled)
+	if report.Speakers < 2 {
+		return nil, newError(ErrInvalidArgument, "at least 2 enrolled speakers are required, got %d", report.Speakers)
+	}
+
+	// 对每个测试文件与全部注册说话人打分
+	var targetScores, nonTargetScores []float32
+	for _, speakerID := range enrolled {
+		for _, path := range testFiles[speakerID] {
+			embedding, err := m.embedTrialFile(path)
+			if err != nil {
+				report.Skipped = append(report.Skipped, fmt.Sprintf("%s: %v", path, err))
+				continue
+			}
+			candidates, err := m.scoreSpeakersIn(scratch, calibrationUID, "", "", "", embedding, report.CurrentThreshold, len(enrolled))
+			if err != nil {
+				return nil, err
+			}
+
+			report.TestFiles++
+			for _, candidate := range candidates {
+				if candidate.SpeakerID == speakerID {
+					targetScores = append(targetScores, candidate.Confidence)
+				} else {
+					nonTargetScores = append(nonTargetScores, candidate.Confidence)
+				}
+			}
+		}
+	}
+	report.TargetTrials = len(targetScores)
+	report.NonTargetTrials = len(nonTargetScores)
+	if report.TargetTrials == 0 || report.NonTargetTrials == 0 {
+		return nil, newError(ErrInvalidArgument, "not enough trials: target=%d, nontarget=%d", report.TargetTrials, report.NonTargetTrials)
+	}
+
+	computeCalibrationMetrics(report, targetScores, nonTargetScores, opts)
+
+	logger.Infof("Calibration finished: speakers=%d, target=%d, nontarget=%d, EER=%.4f@%.4f, minDCF=%.4f@%.4f, recommended=%.4f",
+		report.Speakers, report.TargetTrials, report.NonTargetTrials,
+		report.EER, report.EERThreshold, report.MinDCF, report.MinDCFThreshold, report.RecommendedThreshold)
+
+	return report, nil
+}
+
+// ApplyCalibration 通过配置热加载管理器写回推荐阈值并保存配置文件
+// 启用分数归一化时写入 speaker.score_norm.threshold，否则写入 speaker.threshold
+func ApplyCalibration(writer ConfigWriter, report *CalibrationReport) error {
+	if writer == nil {
+		return fmt.Errorf("config writer is not available")
+	}
+
+	key := "speaker.threshold"
+	if report.ScoreNormalization != "" {
+		key = "speaker.score_norm.threshold"
+	}
+
+	if err := writer.SetConfigValue(key, report.RecommendedThreshold); err != nil {
+		return fmt.Errorf("failed to set %s: %w", key, err)
+	}
+	if err := writer.SaveConfig(); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	report.Applied = true
+	report.AppliedKey = key
+	logger.Infof("Calibration: %s set to %.4f", key, report.RecommendedThreshold)
+	return nil
+}
+
+// ResolveCalibrationDir 解析管理接口传入的标注目录：相对路径基于校准根目录，
+// 清理后的路径（及符号链接解析后的真实路径）必须位于根目录内；未配置根目录时管理接口不可用
+func (m *Manager) ResolveCalibrationDir(dir string) (string, error) {
+	if m.calibrationConfig.RootDir == "" {
+		return "", newError(ErrNotEnabled, "calibration root_dir is not configured, use the eval subcommand instead")
+	}
+	root, err := filepath.Abs(m.calibrationConfig.RootDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid calibration root_dir: %w", err)
+	}
+
+	path := dir
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+	if !isWithinDir(root, path) {
+		return "", newError(ErrForbidden, "dir must be inside the calibration root")
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("invalid calibration root_dir: %w", err)
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", newError(ErrNotFound, "calibration dir %s not found", dir)
+	}
+	if !isWithinDir(realRoot, realPath) {
+		return "", newError(ErrForbidden, "dir must be inside the calibration root")
+	}
+	return realPath, nil
+}
+
+// isWithinDir 判断 path 是否为 root 或其子路径（两者都需是清理后的绝对路径）
+func isWithinDir(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// listTrialFiles 列出标注目录中每个说话人的 WAV 文件（按文件名排序）
+func listTrialFiles(dir string) (map[string][]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trial directory: %w", err)
+	}
+
+	trials := make(map[string][]string)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		speakerDir := filepath.Join(dir, entry.Name())
+		files, err := os.ReadDir(speakerDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", speakerDir, err)
+		}
+		for _, file := range files {
+			if file.IsDir() || !strings.HasSuffix(strings.ToLower(file.Name()), ".wav") {
+				continue
+			}
+			trials[entry.Name()] = append(trials[entry.Name()], filepath.Join(speakerDir, file.Name()))
+		}
+		sort.Strings(trials[entry.Name()])
+	}
+
+	if len(trials) == 0 {
+		return nil, newError(ErrInvalidArgument, "no speaker directories with WAV files found in %s", dir)
+	}
+	return trials, nil
+}
+
+// loadTrialAudio 读取 WAV 文件并使用 VAD 过滤静音（与上传接口的处理一致）
+func (m *Manager) loadTrialAudio(path string) ([]float32, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	audioData, sampleRate, err := decodeWAV(file)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filtered, err := m.filterSilenceWithVADKeepEdges(audioData, sampleRate)
+	if err != nil {
+		return nil, 0, err
+	}
+	return filtered, sampleRate, nil
+}
+
+// embedTrialFile 读取标注音频并提取声纹
+func (m *Manager) embedTrialFile(path string) ([]float32, error) {
+	audioData, sampleRate, err := m.loadTrialAudio(path)
+	if err != nil {
+		return nil, err
+	}
+	embedding, err := m.extractEmbedding(audioData, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedding) != m.embeddingDim {
+		return nil, fmt.Errorf("embedding dimension mismatch: expected %d, got %d", m.embeddingDim, len(embedding))
+	}
+	return embedding, nil
+}
+
+// computeCalibrationMetrics 计算 EER、minDCF 以及候选阈值下的 FAR/FRR
+func computeCalibrationMetrics(report *CalibrationReport, targetScores, nonTargetScores []float32, opts CalibrationOptions) {
+	sort.Slice(targetScores, func(i, j int) bool { return targetScores[i] < targetScores[j] })
+	sort.Slice(nonTargetScores, func(i, j int) bool { return nonTargetScores[i] < nonTargetScores[j] })
+
+	// 以所有出现过的分数作为扫描阈值
+	sweep := make([]float32, 0, len(targetScores)+len(nonTargetScores))
+	sweep = append(sweep, targetScores...)
+	sweep = append(sweep, nonTargetScores...)
+	sort.Slice(sweep, func(i, j int) bool { return sweep[i] < sweep[j] })
+
+	// 归一化 DCF：除以不做任何判断时的最小代价
+	dcfNorm := math.Min(opts.CostMiss*opts.PTarget, opts.CostFA*(1-opts.PTarget))
+
+	report.EER = 1.0
+	report.MinDCF = math.Inf(1)
+	bestGap := math.Inf(1)
+	for _, threshold := range sweep {
+		far, frr := errorRates(targetScores, nonTargetScores, threshold)
+
+		if gap := math.Abs(far - frr); gap < bestGap {
+			bestGap = gap
+			report.EER = (far + frr) / 2
+			report.EERThreshold = threshold
+		}
+
+		dcf := (opts.CostMiss*frr*opts.PTarget + opts.CostFA*far*(1-opts.PTarget)) / dcfNorm
+		if dcf < report.MinDCF {
+			report.MinDCF = dcf
+			report.MinDCFThreshold = threshold
+		}
+	}
+
+	if opts.Criterion == CalibrationCriterionEER {
+		report.RecommendedThreshold = report.EERThreshold
+	} else {
+		report.RecommendedThreshold = report.MinDCFThreshold
+	}
+
+	// 候选阈值：未指定时在分数范围内均匀取点，并附带当前阈值和推荐阈值
+	thresholds := opts.Thresholds
+	if len(thresholds) == 0 {
+		low, high := sweep[0], sweep[len(sweep)-1]
+		step := (high - low) / float32(defaultCalibrationPoints-1)
+		for i := 0; i < defaultCalibrationPoints; i++ {
+			thresholds = append(thresholds, low+step*float32(i))
+		}
+	}
+	thresholds = append(thresholds, report.CurrentThreshold, report.RecommendedThreshold)
+	sort.Slice(thresholds, func(i, j int) bool { return thresholds[i] < thresholds[j] })
+
+	report.Points = make([]CalibrationPoint, 0, len(thresholds))
+	for i, threshold := range thresholds {
+		if i > 0 && threshold == thresholds[i-1] {
+			continue
+		}
+		far, frr := errorRates(targetScores, nonTargetScores, threshold)
+		report.Points = append(report.Points, CalibrationPoint{
+			Threshold: threshold,
+			FAR:       far,
+			FRR:       frr,
+		})
+	}
+}
+
+// errorRates 计算阈值下的误识率（非目标分数 >= 阈值）和拒识率（目标分数 < 阈值），输入需已升序排序
+func errorRates(targetScores, nonTargetScores []float32, threshold float32) (float64, float64) {
+	misses := sort.Search(len(targetScores), func(i int) bool { return targetScores[i] >= threshold })
+	rejected := sort.Search(len(nonTargetScores), func(i int) bool { return nonTargetScores[i] >= threshold })
+
+	frr := float64(misses) / float64(len(targetScores))
+	far := float64(len(nonTargetScores)-rejected) / float64(len(nonTargetScores))
+	return far, frr
+}
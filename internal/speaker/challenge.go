@@ -0,0 +1,250 @@
+package speaker
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
+)
+
+// ChallengeConfig 文本相关验证（挑战-应答）配置
+type ChallengeConfig struct {
+	TTLSeconds       int     `json:"ttl_seconds"`         // 挑战有效期，默认 60 秒
+	Length           int     `json:"length"`              // 提示数字串长度，默认 6
+	MaxCharErrorRate float32 `json:"max_char_error_rate"` // 识别文本与提示的最大字错误率，默认 0.2
+}
+
+// Challenge 下发给客户端的挑战
+type Challenge struct {
+	ID        string    `json:"challenge_id"`
+	Prompt    string    `json:"prompt"` // 需要用户朗读的数字串
+	SpeakerID string    `json:"speaker_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	uid     string
+	agentID string
+}
+
+// ChallengeResult 挑战应答的验证结果：文本和声纹都通过才算通过
+type ChallengeResult struct {
+	ChallengeID   string        `json:"challenge_id"`
+	Prompt        string        `json:"prompt"`
+	Transcript    string        `json:"transcript"`      // ASR 识别原文
+	SpokenDigits  string        `json:"spoken_digits"`   // 从识别原文中提取的数字串
+	CharErrorRate float32       `json:"char_error_rate"` // 数字串与提示的字错误率
+	TextMatched   bool          `json:"text_matched"`
+	Voice         *VerifyResult `json:"voice"`
+	Verified      bool          `json:"verified"`
+}
+
+// challengeStore 未使用的挑战（内存保存，一次性使用）
+type challengeStore struct {
+	mu         sync.Mutex
+	challenges map[string]*Challenge
+}
+
+// SetRecognizer 设置语音识别器（用于挑战-应答验证，未设置时该功能不可用）
+func (m *Manager) SetRecognizer(recognizer *sherpa.OfflineRecognizer) {
+	m.recognizer = recognizer
+}
+
+// IssueChallenge 为声称的说话人生成一次性挑战
+func (m *Manager) IssueChallenge(uid, agentID, speakerID string) (*Challenge, error) {
+	if uid == "" {
+		return nil, newError(ErrInvalidArgument, "uid is required")
+	}
+	if m.recognizer == nil {
+		return nil, newError(ErrNotEnabled, "speech recognition is not enabled")
+	}
+
+	count, err := m.vectorDB.GetSpeakerSampleCount(uid, agentID, speakerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get speaker: %w", err)
+	}
+	if count == 0 {
+		return nil, newError(ErrNotFound, "speaker %s not found", speakerID)
+	}
+
+	id, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := randomDigits(m.challengeConfig.Length)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &Challenge{
+		ID:        id,
+		Prompt:    prompt,
+		SpeakerID: speakerID,
+		ExpiresAt: time.Now().Add(time.Duration(m.challengeConfig.TTLSeconds) * time.Second),
+		uid:       uid,
+		agentID:   agentID,
+	}
+
+	m.challenges.mu.Lock()
+	defer m.challenges.mu.Unlock()
+	// 顺便清理过期的挑战
+	now := time.Now()
+	for key, c := range m.challenges.challenges {
+		if now.After(c.ExpiresAt) {
+			delete(m.challenges.challenges, key)
+		}
+	}
+	m.challenges.challenges[id] = challenge
+	return challenge, nil
+}
+
+// takeChallenge 取出并删除挑战（无论验证是否通过，挑战只能使用一次）
+func (m *Manager) takeChallenge(id, uid, agentID string) (*Challenge, error) {
+	m.challenges.mu.Lock()
+	challenge, ok := m.challenges.challenges[id]
+	delete(m.challenges.challenges, id)
+	m.challenges.mu.Unlock()
+
+	if !ok {
+		return nil, newError(ErrExpired, "challenge %s not found or already used", id)
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return nil, newError(ErrExpired, "challenge %s expired", id)
+	}
+	if challenge.uid != uid || challenge.agentID != agentID {
+		return nil, newError(ErrForbidden, "challenge %s belongs to different uid or agent_id", id)
+	}
+	return challenge, nil
+}
+
+// VerifyChallenge 校验挑战应答：ASR 识别的数字串必须与提示一致，且声纹通过对声称说话人的验证
+func (m *Manager) VerifyChallenge(challengeID, uid, agentID string, audioData []float32, sampleRate int, opts VerifyOptions) (*ChallengeResult, error) {
+	if m.recognizer == nil {
+		return nil, newError(ErrNotEnabled, "speech recognition is not enabled")
+	}
+
+	challenge, err := m.takeChallenge(challengeID, uid, agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 过滤静音，保留前后 100ms
+	filtered, err := m.filterSilenceWithVADKeepEdges(audioData, sampleRate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter silence: %w", err)
+	}
+	if len(filtered) == 0 {
+		return nil, newError(ErrInsufficientAudio, "no speech detected")
+	}
+
+	transcript := m.transcribe(filtered, sampleRate)
+	spoken := extractSpokenDigits(transcript)
+	cer := float32(editDistance([]rune(challenge.Prompt), []rune(spoken))) / float32(len(challenge.Prompt))
+
+	voice, err := m.VerifySpeaker(uid, agentID, challenge.SpeakerID, filtered, sampleRate, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ChallengeResult{
+		ChallengeID:   challenge.ID,
+		Prompt:        challenge.Prompt,
+		Transcript:    transcript,
+		SpokenDigits:  spoken,
+		CharErrorRate: cer,
+		TextMatched:   cer <= m.challengeConfig.MaxCharErrorRate,
+		Voice:         voice,
+	}
+	result.Verified = result.TextMatched && voice.Verified
+	return result, nil
+}
+
+// transcribe 使用离线识别器识别整段音频
+func (m *Manager) transcribe(audioData []float32, sampleRate int) string {
+	stream := sherpa.NewOfflineStream(m.recognizer)
+	defer sherpa.DeleteOfflineStream(stream)
+	stream.AcceptWaveform(sampleRate, audioData)
+	m.recognizer.Decode(stream)
+	result := stream.GetResult()
+	if result == nil {
+		return ""
+	}
+	return result.Text
+}
+
+// spokenDigitWords 识别结果中可能出现的数字写法（SenseVoice 对中文数字可能输出汉字）
+var spokenDigitWords = map[string]string{
+	"zero": "0", "oh": "0", "one": "1", "two": "2", "three": "3", "four": "4",
+	"five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+}
+
+var spokenDigitRunes = map[rune]rune{
+	'零': '0', '〇': '0', '洞': '0', '一': '1', '幺': '1', '二': '2', '两': '2', '三': '3', '四': '4',
+	'五': '5', '六': '6', '七': '7', '拐': '7', '八': '8', '九': '9', '勾': '9',
+}
+
+// extractSpokenDigits 从识别文本中提取数字串（支持阿拉伯数字、中文数字和英文单词）
+func extractSpokenDigits(text string) string {
+	var digits strings.Builder
+	var word strings.Builder
+	flushWord := func() {
+		if d, ok := spokenDigitWords[strings.ToLower(word.String())]; ok {
+			digits.WriteString(d)
+		}
+		word.Reset()
+	}
+
+	for _, r := range text {
+		switch {
+		case r >= '0' && r <= '9':
+			flushWord()
+			digits.WriteRune(r)
+		case spokenDigitRunes[r] != 0:
+			flushWord()
+			digits.WriteRune(spokenDigitRunes[r])
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			word.WriteRune(r)
+		default:
+			flushWord()
+		}
+	}
+	flushWord()
+	return digits.String()
+}
+
+// editDistance 计算两个字符序列的编辑距离
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// randomDigits 生成指定长度的随机数字串
+func randomDigits(length int) (string, error) {
+	digits := make([]byte, length)
+	for i := range digits {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate challenge: %w", err)
+		}
+		digits[i] = byte('0' + n.Int64())
+	}
+	return string(digits), nil
+}
@@ -0,0 +1,57 @@
+package speaker
+
+import "fmt"
+
+// CompareResult 两段音频的一对一比对结果
+type CompareResult struct {
+	Similarity      float32 `json:"similarity"` // 余弦相似度
+	Threshold       float32 `json:"threshold"`
+	SameSpeaker     bool    `json:"same_speaker"`
+	SpeechDurationA float64 `json:"speech_duration_a"` // 音频 A 的净语音时长（秒，VAD 检测为语音的帧，不含保留的静音）
+	SpeechDurationB float64 `json:"speech_duration_b"` // 音频 B 的净语音时长（秒，VAD 检测为语音的帧，不含保留的静音）
+}
+
+// CompareAudio 比对两段音频是否为同一说话人，不读写向量数据库
+// threshold 为可选参数，未提供时使用余弦相似度阈值（分数归一化依赖注册库，这里不适用）
+func (m *Manager) CompareAudio(audioA []float32, sampleRateA int, audioB []float32, sampleRateB int, threshold ...float32) (*CompareResult, error) {
+	useThreshold := m.GetThreshold()
+	if len(threshold) > 0 && threshold[0] > 0 {
+		useThreshold = threshold[0]
+	}
+
+	embeddingA, durationA, err := m.embedSpeech(audioA, sampleRateA)
+	if err != nil {
+		return nil, fmt.Errorf("audio_a: %w", err)
+	}
+	embeddingB, durationB, err := m.embedSpeech(audioB, sampleRateB)
+	if err != nil {
+		return nil, fmt.Errorf("audio_b: %w", err)
+	}
+
+	similarity := dotProduct(normalizeVector(embeddingA), normalizeVector(embeddingB))
+	return &CompareResult{
+		Similarity:      similarity,
+		Threshold:       useThreshold,
+		SameSpeaker:     similarity >= useThreshold,
+		SpeechDurationA: durationA,
+		SpeechDurationB: durationB,
+	}, nil
+}
+
+// embedSpeech 过滤静音（每段语音前后保留 100ms）后提取声纹，同时返回净语音时长（秒）
+func (m *Manager) embedSpeech(audioData []float32, sampleRate int) ([]float32, float64, error) {
+	filtered, speechSamples, err := m.gateSpeech(audioData, sampleRate, 100)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to filter silence: %w", err)
+	}
+	if speechSamples == 0 {
+		return nil, 0, newError(ErrInsufficientAudio, "no speech detected")
+	}
+	duration := float64(speechSamples) / float64(sampleRate)
+
+	embedding, err := m.extractEmbedding(filtered, sampleRate)
+	if err != nil {
+		return nil, duration, fmt.Errorf("failed to extract embedding: %w", err)
+	}
+	return embedding, duration, nil
+}
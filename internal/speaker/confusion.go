@@ -0,0 +1,152 @@
+package speaker
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ConfusionReport 租户内说话人之间的可混淆性报告
+type ConfusionReport struct {
+	UID       string                  `json:"uid"`
+	AgentID   string                  `json:"agent_id"`
+	WarnLevel float32                 `json:"warn_level"` // 质心相似度达到该值的说话人对会被列出
+	Speakers  []SpeakerSeparation     `json:"speakers"`
+	Pairs     []ConfusableSpeakerPair `json:"pairs"`
+	Matrix    [][]float32             `json:"matrix,omitempty"` // 质心相似度矩阵，行列顺序与 Speakers 一致
+}
+
+// SpeakerSeparation 单个说话人的类内/类间相似度
+type SpeakerSeparation struct {
+	AgentID     string `json:"agent_id"` // 未按 agent_id 过滤时区分不同 agent 下的同名 speaker_id
+	SpeakerID   string `json:"speaker_id"`
+	SpeakerName string `json:"speaker_name"`
+	SampleCount int    `json:"sample_count"`
+	// 样本与自身质心的平均相似度（只有一个样本时为空）
+	IntraSimilarity *float32 `json:"intra_similarity,omitempty"`
+	// 质心最相似的其他说话人
+	NearestSpeakerID  string   `json:"nearest_speaker_id,omitempty"`
+	NearestSimilarity *float32 `json:"nearest_similarity,omitempty"`
+	// 类内相似度与最近类间相似度之差，越小越容易混淆
+	Margin *float32 `json:"margin,omitempty"`
+}
+
+// ConfusableSpeakerPair 质心相似度超过警告值的说话人对
+type ConfusableSpeakerPair struct {
+	SpeakerA   string  `json:"speaker_a"`
+	SpeakerB   string  `json:"speaker_b"`
+	Similarity float32 `json:"similarity"`
+}
+
+// ConfusionReport 计算 uid/agent_id 下所有说话人质心之间的相似度矩阵
+// warnLevel <= 0 时使用当前余弦相似度阈值
+func (m *Manager) ConfusionReport(uid, agentID string, warnLevel float32, includeMatrix bool) (*ConfusionReport, error) {
+	if uid == "" {
+		return nil, newError(ErrInvalidArgument, "uid is required")
+	}
+	if warnLevel <= 0 {
+		warnLevel = m.GetThreshold()
+	}
+
+	groups, err := m.vectorDB.GetSpeakerEmbeddings(uid, agentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get speaker embeddings: %w", err)
+	}
+
+	report := &ConfusionReport{
+		UID:       uid,
+		AgentID:   agentID,
+		WarnLevel: warnLevel,
+		Speakers:  make([]SpeakerSeparation, len(groups)),
+		Pairs:     make([]ConfusableSpeakerPair, 0),
+	}
+
+	// 计算每个说话人的质心（样本先归一化再平均，最后再次归一化）和类内相似度
+	centroids := make([][]float32, len(groups))
+	for i, group := range groups {
+		normalized := make([][]float32, len(group.Embeddings))
+		for j, embedding := range group.Embeddings {
+			normalized[j] = normalizeVector(embedding)
+		}
+		centroids[i] = normalizeVector(meanVector(normalized))
+
+		report.Speakers[i] = SpeakerSeparation{
+			AgentID:     group.AgentID,
+			SpeakerID:   group.SpeakerID,
+			SpeakerName: group.SpeakerName,
+			SampleCount: len(group.Embeddings),
+		}
+		if len(normalized) > 1 {
+			var sum float32
+			for _, embedding := range normalized {
+				sum += dotProduct(embedding, centroids[i])
+			}
+			intra := sum / float32(len(normalized))
+			report.Speakers[i].IntraSimilarity = &intra
+		}
+	}
+
+	// 两两计算质心相似度
+	matrix := make([][]float32, len(groups))
+	for i := range matrix {
+		matrix[i] = make([]float32, len(groups))
+		matrix[i][i] = 1
+	}
+	for i := 0; i < len(groups); i++ {
+		for j := i + 1; j < len(groups); j++ {
+			similarity := dotProduct(centroids[i], centroids[j])
+			matrix[i][j] = similarity
+			matrix[j][i] = similarity
+			if similarity >= warnLevel {
+				report.Pairs = append(report.Pairs, ConfusableSpeakerPair{
+					SpeakerA:   groups[i].SpeakerID,
+					SpeakerB:   groups[j].SpeakerID,
+					Similarity: similarity,
+				})
+			}
+		}
+	}
+	sort.Slice(report.Pairs, func(i, j int) bool { return report.Pairs[i].Similarity > report.Pairs[j].Similarity })
+
+	// 每个说话人的最近邻和类内/类间间隔
+	for i := range groups {
+		nearest := -1
+		for j := range groups {
+			if j != i && (nearest < 0 || matrix[i][j] > matrix[i][nearest]) {
+				nearest = j
+			}
+		}
+		if nearest < 0 {
+			continue
+		}
+		similarity := matrix[i][nearest]
+		speaker := &report.Speakers[i]
+		speaker.NearestSpeakerID = groups[nearest].SpeakerID
+		speaker.NearestSimilarity = &similarity
+		if speaker.IntraSimilarity != nil {
+			margin := *speaker.IntraSimilarity - similarity
+			speaker.Margin = &margin
+		}
+	}
+
+	if includeMatrix {
+		report.Matrix = matrix
+	}
+	return report, nil
+}
+
+// meanVector 计算多个向量的平均值
+func meanVector(vectors [][]float32) []float32 {
+	if len(vectors) == 0 {
+		return nil
+	}
+	mean := make([]float32, len(vectors[0]))
+	for _, vector := range vectors {
+		for i, v := range vector {
+			mean[i] += v
+		}
+	}
+	for i := range mean {
+		mean[i] /= float32(len(vectors))
+	}
+	return mean
+}
@@ -0,0 +1,73 @@
+package speaker
+
+import (
+	"errors"
+	"fmt"
+)
+
+// minEmbedWindowSeconds 分窗提取的最小窗口长度（秒），过短的窗口无法得到稳定的声纹
+const minEmbedWindowSeconds = 1.0
+
+// EmbedResult 声纹向量提取结果
+type EmbedResult struct {
+	Model          string            `json:"model"`
+	EmbeddingDim   int               `json:"embedding_dim"`
+	SpeechDuration float64           `json:"speech_duration"` // 净语音时长（秒，VAD 检测为语音的帧，不含保留的静音）
+	Embedding      []float32         `json:"embedding"`       // L2 归一化后的声纹向量
+	Windows        []WindowEmbedding `json:"windows,omitempty"`
+}
+
+// WindowEmbedding 单个时间窗口的声纹向量（时间相对于原始音频）
+type WindowEmbedding struct {
+	Start          float64   `json:"start"`
+	End            float64   `json:"end"`
+	SpeechDuration float64   `json:"speech_duration"`
+	Embedding      []float32 `json:"embedding"`
+}
+
+// EmbedAudio 提取整段音频的 L2 归一化声纹向量
+// windowSeconds > 0 时额外按该长度切分原始音频，逐窗口提取声纹；没有语音或语音过短的窗口会被跳过
+func (m *Manager) EmbedAudio(audioData []float32, sampleRate int, windowSeconds float64) (*EmbedResult, error) {
+	if windowSeconds > 0 && windowSeconds < minEmbedWindowSeconds {
+		return nil, newError(ErrInvalidArgument, "invalid window: must be at least %.0f second", minEmbedWindowSeconds)
+	}
+
+	embedding, duration, err := m.embedSpeech(audioData, sampleRate)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &EmbedResult{
+		Model:          m.modelName,
+		EmbeddingDim:   len(embedding),
+		SpeechDuration: duration,
+		Embedding:      normalizeVector(embedding),
+	}
+
+	if windowSeconds > 0 {
+		windowSize := int(windowSeconds * float64(sampleRate))
+		result.Windows = make([]WindowEmbedding, 0, len(audioData)/windowSize+1)
+		for start := 0; start < len(audioData); start += windowSize {
+			end := start + windowSize
+			if end > len(audioData) {
+				end = len(audioData)
+			}
+
+			windowEmbedding, windowDuration, err := m.embedSpeech(audioData[start:end], sampleRate)
+			if err != nil {
+				if errors.Is(err, ErrInsufficientAudio) {
+					continue
+				}
+				return nil, fmt.Errorf("window at %.2fs: %w", float64(start)/float64(sampleRate), err)
+			}
+			result.Windows = append(result.Windows, WindowEmbedding{
+				Start:          float64(start) / float64(sampleRate),
+				End:            float64(end) / float64(sampleRate),
+				SpeechDuration: windowDuration,
+				Embedding:      normalizeVector(windowEmbedding),
+			})
+		}
+	}
+
+	return result, nil
+}
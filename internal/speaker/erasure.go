@@ -0,0 +1,210 @@
+package speaker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"voice_server/internal/logger"
+)
+
+// erasureLogFile 删除回执的审计记录文件（JSON Lines，只追加）
+const erasureLogFile = "erasure_receipts.jsonl"
+
+// erasureLogMutex 保证并发删除时审计记录逐行写入
+var erasureLogMutex sync.Mutex
+
+// savedAudioNamePattern 旧版本平铺保存的音频文件名：[register_]YYYYMMDD_HHMMSS_<uid>[_<agent_id>][_<point_id>].wav
+var savedAudioNamePattern = regexp.MustCompile(`^(?:register_)?\d{8}_\d{6}_(.+)\.wav$`)
+
+// ErasureReceipt 删除回执
+type ErasureReceipt struct {
+	ReceiptID         string    `json:"receipt_id"`
+	UID               string    `json:"uid"`
+	AgentID           string    `json:"agent_id,omitempty"` // 为空表示删除该 uid 下所有 agent 的数据
+	RequestedAt       time.Time `json:"requested_at"`
+	CompletedAt       time.Time `json:"completed_at"`
+	SpeakerIDs        []string  `json:"speaker_ids"`
+	PointsDeleted     int       `json:"points_deleted"`
+	AudioFilesDeleted []string  `json:"audio_files_deleted"` // 已删除的音频文件名
+	AudioBytesDeleted int64     `json:"audio_bytes_deleted"`
+	UnattributedAudio []string  `json:"unattributed_audio,omitempty"` // 无法确定归属、未删除的旧版本音频，需要人工确认
+	Errors            []string  `json:"errors,omitempty"`
+	Complete          bool      `json:"complete"` // 向量数据和音频文件都已删除干净（存在无法确定归属的音频时为 false）
+}
+
+// EraseUser 删除 uid（可选 agentID）的全部数据：Qdrant 中的样本点和保存的注册/识别音频
+// 删除回执会追加到数据目录下的审计记录中
+func (m *Manager) EraseUser(uid, agentID string) (*ErasureReceipt, error) {
+	if uid == "" {
+		return nil, newError(ErrInvalidArgument, "uid is required")
+	}
+
+	receiptID, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+	receipt := &ErasureReceipt{
+		ReceiptID:         receiptID,
+		UID:               uid,
+		AgentID:           agentID,
+		RequestedAt:       time.Now(),
+		SpeakerIDs:        make([]string, 0),
+		AudioFilesDeleted: make([]string, 0),
+	}
+
+	// 先记下所有 Point ID，注册音频文件名以 Point ID 结尾，据此精确匹配
+	points, err := m.vectorDB.ListTenantPoints(uid, agentID)
+	if err != nil {
+		return nil, err
+	}
+	speakers := make(map[string]bool)
+	for _, speakerID := range points {
+		if speakerID != "" && !speakers[speakerID] {
+			speakers[speakerID] = true
+			receipt.SpeakerIDs = append(receipt.SpeakerIDs, speakerID)
+		}
+	}
+	sort.Strings(receipt.SpeakerIDs)
+
+	if err := m.vectorDB.DeleteTenant(uid, agentID); err != nil {
+		receipt.Errors = append(receipt.Errors, err.Error())
+	} else {
+		receipt.PointsDeleted = len(points)
+	}
+
+	// 删除期间可能有并发注册写入，删除后再确认一次
+	if remaining, err := m.vectorDB.CountSamples(uid, agentID); err != nil {
+		receipt.Errors = append(receipt.Errors, err.Error())
+	} else if remaining > 0 {
+		receipt.Errors = append(receipt.Errors, fmt.Sprintf("%d points remain after deletion", remaining))
+	}
+
+	// 按租户分区的音频归档直接删除整个租户目录
+	files, bytes, err := m.audioArchive.EraseTenant(uid, agentID)
+	if err != nil {
+		receipt.Errors = append(receipt.Errors, err.Error())
+	}
+	receipt.AudioFilesDeleted = append(receipt.AudioFilesDeleted, files...)
+	receipt.AudioBytesDeleted += bytes
+
+	// 旧版本平铺保存的音频
+	m.eraseSavedAudio(m.audioArchive.Root(), uid, agentID, points, receipt)
+
+	receipt.CompletedAt = time.Now()
+	receipt.Complete = len(receipt.Errors) == 0 && len(receipt.UnattributedAudio) == 0
+
+	if err := m.appendErasureLog(receipt); err != nil {
+		// 审计记录写入失败不影响已完成的删除，但必须让调用方知道
+		receipt.Errors = append(receipt.Errors, err.Error())
+		receipt.Complete = false
+	}
+
+	logger.Infof("Erased data for uid=%s agent_id=%s: speakers=%d, points=%d, audio_files=%d, receipt=%s",
+		uid, agentID, len(receipt.SpeakerIDs), receipt.PointsDeleted, len(receipt.AudioFilesDeleted), receipt.ReceiptID)
+	return receipt, nil
+}
+
+// eraseSavedAudio 删除旧版本平铺保存在根目录中属于该 uid/agentID 的音频文件
+// 删除文件名以该租户 Point ID 结尾的注册音频，以及 uid、agent_id 都不含 "_" 时文件名与租户完全相符的音频；
+// 其余以租户为前缀的文件无法确定归属（平铺的文件名无法区分 uid "a" + agent_id "b" 与 uid "a_b"），记入回执但不删除
+func (m *Manager) eraseSavedAudio(dir, uid, agentID string, points map[string]string, receipt *ErasureReceipt) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return
+	}
+
+	tenant := sanitizeFileComponent(uid)
+	if agentID != "" {
+		tenant += "_" + sanitizeFileComponent(agentID)
+	}
+	// 各部分都不含分隔符时，与租户完全相符的文件名只可能来自该租户
+	unambiguous := !strings.Contains(sanitizeFileComponent(uid), "_") && !strings.Contains(sanitizeFileComponent(agentID), "_")
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			receipt.Errors = append(receipt.Errors, err.Error())
+			return nil
+		}
+		if d.IsDir() {
+			// 租户分区的归档已单独删除
+			if path == filepath.Join(dir, archiveTenantsDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		match := savedAudioNamePattern.FindStringSubmatch(d.Name())
+		if match == nil {
+			return nil
+		}
+
+		rest := match[1]
+		owned := false
+		if i := strings.LastIndex(rest, "_"); i >= 0 {
+			_, owned = points[rest[i+1:]]
+		}
+		if !owned && !(unambiguous && rest == tenant) {
+			if rest == tenant || strings.HasPrefix(rest, tenant+"_") {
+				receipt.UnattributedAudio = append(receipt.UnattributedAudio, d.Name())
+			}
+			return nil
+		}
+
+		info, statErr := d.Info()
+		if err := os.Remove(path); err != nil {
+			receipt.Errors = append(receipt.Errors, fmt.Sprintf("failed to delete %s: %v", d.Name(), err))
+			return nil
+		}
+		receipt.AudioFilesDeleted = append(receipt.AudioFilesDeleted, d.Name())
+		if statErr == nil {
+			receipt.AudioBytesDeleted += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		receipt.Errors = append(receipt.Errors, fmt.Sprintf("failed to scan audio directory: %v", err))
+	}
+}
+
+// appendErasureLog 将删除回执追加到审计记录
+func (m *Manager) appendErasureLog(receipt *ErasureReceipt) error {
+	dir := m.dataDir
+	if dir == "" {
+		dir = "data/speaker"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	line, err := json.Marshal(receipt)
+	if err != nil {
+		return fmt.Errorf("failed to encode erasure receipt: %w", err)
+	}
+
+	erasureLogMutex.Lock()
+	defer erasureLogMutex.Unlock()
+
+	file, err := os.OpenFile(filepath.Join(dir, erasureLogFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open erasure log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write erasure log: %w", err)
+	}
+	return nil
+}
+
+// sanitizeFileComponent 与保存音频时的文件名清理规则保持一致
+func sanitizeFileComponent(s string) string {
+	s = strings.ReplaceAll(s, "/", "_")
+	s = strings.ReplaceAll(s, "\\", "_")
+	return strings.ReplaceAll(s, ":", "_")
+}
@@ -0,0 +1,97 @@
+package speaker
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"voice_server/internal/pool"
+)
+
+// 错误分类，用 errors.Is 判断
+var (
+	ErrInvalidArgument   = errors.New("invalid argument")
+	ErrNotFound          = errors.New("not found")
+	ErrUnauthorized      = errors.New("unauthorized") // 缺少或错误的管理接口令牌
+	ErrForbidden         = errors.New("forbidden")
+	ErrDuplicateSpeaker  = errors.New("duplicate speaker")
+	ErrConflict          = errors.New("conflict") // 与现有数据冲突（如合并、拆分的目标已存在）
+	ErrExpired           = errors.New("expired")
+	ErrInsufficientAudio = errors.New("insufficient audio") // 没有检测到语音或有效语音太短
+	ErrQualityRejected   = errors.New("quality rejected")   // 音频质量检查未通过
+	ErrNotEnabled        = errors.New("not enabled")        // 功能未启用（如未配置语音识别、审计日志）
+	ErrStoreUnavailable  = errors.New("store unavailable")  // 向量数据库不可用
+)
+
+// 错误码（错误响应中的 code 字段）
+const (
+	CodeInvalidArgument   = "invalid_argument"
+	CodeNotFound          = "not_found"
+	CodeUnauthorized      = "unauthorized"
+	CodeForbidden         = "forbidden"
+	CodeDuplicateSpeaker  = "duplicate_speaker"
+	CodeConflict          = "conflict"
+	CodeExpired           = "expired"
+	CodeInsufficientAudio = "insufficient_audio"
+	CodeQualityRejected   = "quality_rejected"
+	CodeNotEnabled        = "not_enabled"
+	CodeNotImplemented    = "not_implemented"
+	CodeBusy              = "busy"
+	CodeStoreUnavailable  = "store_unavailable"
+	CodeInternal          = "internal"
+)
+
+// Error 带分类的错误，Error() 返回具体信息，errors.Is 匹配分类
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Kind }
+
+// newError 创建带分类的错误
+func newError(kind error, format string, args ...any) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// errorCodes 错误分类到错误码和 HTTP 状态码的映射，按顺序匹配
+var errorCodes = []struct {
+	kind   error
+	code   string
+	status int
+}{
+	{ErrInvalidArgument, CodeInvalidArgument, http.StatusBadRequest},
+	{ErrNotFound, CodeNotFound, http.StatusNotFound},
+	{ErrUnauthorized, CodeUnauthorized, http.StatusUnauthorized},
+	{ErrForbidden, CodeForbidden, http.StatusForbidden},
+	{ErrDuplicateSpeaker, CodeDuplicateSpeaker, http.StatusConflict},
+	{ErrConflict, CodeConflict, http.StatusConflict},
+	{ErrExpired, CodeExpired, http.StatusGone},
+	{ErrInsufficientAudio, CodeInsufficientAudio, http.StatusUnprocessableEntity},
+	{ErrQualityRejected, CodeQualityRejected, http.StatusUnprocessableEntity},
+	{ErrNotEnabled, CodeNotEnabled, http.StatusServiceUnavailable},
+	{pool.ErrExtractorCheckoutTimeout, CodeBusy, http.StatusServiceUnavailable},
+	{ErrStoreUnavailable, CodeStoreUnavailable, http.StatusServiceUnavailable},
+}
+
+// ErrorCode 返回错误对应的错误码和 HTTP 状态码，未分类的错误为 internal / 500
+// Qdrant 的连接类错误（含熔断）归为 store_unavailable
+func ErrorCode(err error) (string, int) {
+	for _, entry := range errorCodes {
+		if errors.Is(err, entry.kind) {
+			return entry.code, entry.status
+		}
+	}
+	if isTransientQdrantError(err) {
+		return CodeStoreUnavailable, http.StatusServiceUnavailable
+	}
+	return CodeInternal, http.StatusInternalServerError
+}
+
+// errorCodeOf 返回错误对应的错误码（WebSocket 错误消息使用）
+func errorCodeOf(err error) string {
+	code, _ := ErrorCode(err)
+	return code
+}
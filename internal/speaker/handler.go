@@ -3,26 +3,28 @@ package speaker
 import (
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 	"voice_server/config"
 	"voice_server/internal/logger"
+	"voice_server/internal/middleware"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-audio/audio"
 	"github.com/go-audio/wav"
 	"github.com/gorilla/websocket"
 )
 
 // Handler 声纹识别HTTP处理器
 type Handler struct {
-	manager *Manager
+	manager      *Manager
+	configWriter ConfigWriter // 用于写回校准阈值（可选）
 }
 
 // NewHandler 创建新的处理器
@@ -32,6 +34,11 @@ func NewHandler(manager *Manager) *Handler {
 	}
 }
 
+// SetConfigWriter 设置配置写回接口（用于校准后写回推荐阈值）
+func (h *Handler) SetConfigWriter(writer ConfigWriter) {
+	h.configWriter = writer
+}
+
 // getUIDFromRequest 从请求中提取 UID
 // 优先级：请求头 X-User-ID > 查询参数 uid > 表单字段 uid
 func getUIDFromRequest(c *gin.Context) string {
@@ -88,6 +95,90 @@ func getAgentIDFromRequest(c *gin.Context) string {
 	return ""
 }
 
+// getTopKFromRequest 从请求中提取 top_k 参数
+// 优先级：查询参数 top_k > 表单字段 top_k，未提供或无效时返回 0
+func getTopKFromRequest(c *gin.Context) int {
+	topKStr := c.Query("top_k")
+	if topKStr == "" {
+		topKStr = c.PostForm("top_k")
+	}
+	if topKStr == "" {
+		return 0
+	}
+
+	topK, err := parseInt(topKStr)
+	if err != nil || topK <= 0 {
+		logger.Warnf("Invalid top_k parameter '%s', using default", topKStr)
+		return 0
+	}
+	return topK
+}
+
+// getThresholdFromRequest 从请求中提取 threshold 参数
+// 优先级：查询参数 threshold > 表单字段 threshold，未提供时返回 0（使用默认阈值）
+// 参数无效时返回 400 错误并返回 false
+func getThresholdFromRequest(c *gin.Context) (float32, bool) {
+	thresholdStr := c.Query("threshold")
+	if thresholdStr == "" {
+		thresholdStr = c.PostForm("threshold")
+	}
+	if thresholdStr == "" {
+		return 0, true
+	}
+
+	threshold, err := parseFloat32(thresholdStr)
+	if err != nil || !(threshold > 0) {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("invalid threshold '%s', must be a positive number", thresholdStr))
+		return 0, false
+	}
+	return threshold, true
+}
+
+// audit 补全客户端 IP、请求 ID 和状态码后写入审计记录，在处理函数中 defer 调用
+// 未显式给出判定结果时按状态码记为 success 或 error
+func (h *Handler) audit(c *gin.Context, entry *AuditEntry) {
+	entry.ClientIP = c.ClientIP()
+	entry.RequestID = middleware.GetRequestID(c)
+	if entry.Status == 0 {
+		entry.Status = c.Writer.Status()
+	}
+	if entry.Decision == "" {
+		entry.Decision = AuditDecisionSuccess
+		if entry.Status >= http.StatusBadRequest {
+			entry.Decision = AuditDecisionError
+		}
+	}
+	if entry.Error == "" {
+		entry.Error = c.GetString(errorMessageKey)
+	}
+	h.manager.RecordAudit(entry)
+}
+
+// errorMessageKey 错误信息在 gin.Context 中的键（审计日志使用）
+const errorMessageKey = "speaker_error"
+
+// respondError 按错误分类返回统一的错误响应，extra 为附加字段
+func respondError(c *gin.Context, err error, extra ...gin.H) {
+	code, status := ErrorCode(err)
+	errorResponse(c, status, code, err.Error(), extra...)
+}
+
+// errorResponse 返回错误响应 {"error": 错误信息, "code": 错误码, "request_id": 请求 ID}
+func errorResponse(c *gin.Context, status int, code, message string, extra ...gin.H) {
+	body := gin.H{
+		"error":      message,
+		"code":       code,
+		"request_id": middleware.GetRequestID(c),
+	}
+	for _, fields := range extra {
+		for k, v := range fields {
+			body[k] = v
+		}
+	}
+	c.Set(errorMessageKey, message)
+	c.JSON(status, body)
+}
+
 // RegisterRoutes 注册路由
 func (h *Handler) RegisterRoutes(router *gin.Engine) {
 	speakerGroup := router.Group("/api/v1/speaker")
@@ -101,12 +192,34 @@ func (h *Handler) RegisterRoutes(router *gin.Engine) {
 		// 声纹验证
 		speakerGroup.POST("/verify/:speaker_id", h.VerifySpeaker)
 
+		// 两段音频一对一比对（不需要注册）
+		speakerGroup.POST("/compare", h.CompareSpeakers)
+
+		// 提取声纹向量（不写入数据库）
+		speakerGroup.POST("/embed", h.EmbedAudio)
+
+		// 文本相关验证：下发挑战，朗读后同时校验识别文本和声纹
+		speakerGroup.POST("/challenge", h.IssueChallenge)
+		speakerGroup.POST("/challenge/:challenge_id/verify", h.VerifyChallenge)
+
 		// 获取所有说话人
 		speakerGroup.GET("/list", h.GetAllSpeakers)
 
 		// 删除说话人（支持两种方式：查询参数 uuid 或路径参数 speaker_id）
 		speakerGroup.DELETE("/:speaker_id", h.DeleteSpeaker) // 支持 DELETE /api/v1/speaker/:speaker_id
 
+		// 修改说话人名称
+		speakerGroup.PATCH("/:speaker_id", h.RenameSpeaker)
+
+		// 合并、拆分说话人
+		speakerGroup.POST("/:speaker_id/merge", h.MergeSpeaker)
+		speakerGroup.POST("/:speaker_id/split", h.SplitSpeaker)
+
+		// 样本管理：查询、追加、删除单个样本
+		speakerGroup.GET("/:speaker_id/samples", h.ListSpeakerSamples)
+		speakerGroup.POST("/:speaker_id/samples", h.AddSpeakerSample)
+		speakerGroup.DELETE("/:speaker_id/samples/:sample_index", h.DeleteSpeakerSample)
+
 		// 获取数据库统计信息
 		speakerGroup.GET("/stats", h.GetStats)
 
@@ -116,26 +229,53 @@ func (h *Handler) RegisterRoutes(router *gin.Engine) {
 
 		// WebSocket 流式识别接口
 		speakerGroup.GET("/identify_ws", h.IdentifySpeakerWebSocket)
+
+		// WebSocket 流式注册接口（一个连接可录制多个样本）
+		speakerGroup.GET("/register_ws", h.RegisterSpeakerWebSocket)
+	}
+
+	// 管理接口：需要携带 speaker.admin.token 配置的令牌，未配置令牌时不可用
+	adminGroup := speakerGroup.Group("/admin", h.adminAuth())
+	{
+		// 阈值校准
+		adminGroup.POST("/calibrate", h.CalibrateThreshold)
+
+		// 导出、导入说话人数据
+		adminGroup.GET("/export", h.ExportSpeakers)
+		adminGroup.POST("/import", h.ImportSpeakers)
+
+		// 可混淆说话人报告
+		adminGroup.GET("/confusion", h.GetConfusionReport)
+
+		// 删除 uid（可选 agent_id）的全部数据并返回删除回执
+		adminGroup.POST("/erase", h.EraseUser)
+
+		// 查询归档音频元数据
+		adminGroup.GET("/audio", h.ListArchivedAudio)
+
+		// 查询审计记录
+		adminGroup.GET("/audit", h.QueryAudit)
 	}
 }
 
 // RegisterSpeaker 注册声纹
 func (h *Handler) RegisterSpeaker(c *gin.Context) {
+	entry := &AuditEntry{Action: AuditActionRegister}
+	defer h.audit(c, entry)
+
 	// 获取 UID
 	uid := getUIDFromRequest(c)
+	entry.UID = uid
 	if uid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "uid is required (X-User-ID header, uid query param, or uid form field)",
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
 		return
 	}
 
 	// 获取 Agent ID（必填）
 	agentID := getAgentIDFromRequest(c)
+	entry.AgentID = agentID
 	if agentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "agent_id is required (X-Agent-ID header, agent_id query param, or agent_id form field)",
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "agent_id is required (X-Agent-ID header, agent_id query param, or agent_id form field)")
 		return
 	}
 
@@ -143,34 +283,27 @@ func (h *Handler) RegisterSpeaker(c *gin.Context) {
 	speakerID := c.PostForm("speaker_id")
 	speakerName := c.PostForm("speaker_name")
 	uuid := c.PostForm("uuid") // 新增：UUID 参数
+	entry.SpeakerID = speakerID
 
 	if speakerID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "speaker_id is required",
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "speaker_id is required")
 		return
 	}
 
 	if speakerName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "speaker_name is required",
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "speaker_name is required")
 		return
 	}
 
 	if uuid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "uuid is required",
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uuid is required")
 		return
 	}
 
 	// 获取音频文件
 	file, header, err := c.Request.FormFile("audio")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "audio file is required",
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "audio file is required")
 		return
 	}
 	defer file.Close()
@@ -178,47 +311,66 @@ func (h *Handler) RegisterSpeaker(c *gin.Context) {
 	// 解析音频数据
 	audioData, sampleRate, err := h.parseAudioFile(file, header)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("failed to parse audio file: %v", err),
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("failed to parse audio file: %v", err))
 		return
 	}
 
 	// 使用VAD过滤静音，保留前后100ms的静音
 	filteredAudio, err := h.manager.FilterSilenceWithVADKeepEdges(audioData, sampleRate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to filter silence: %v", err),
-		})
+		respondError(c, fmt.Errorf("failed to filter silence: %w", err))
 		return
 	}
 
 	// 注册声纹（使用过滤后的音频）
-	err = h.manager.RegisterSpeaker(uid, agentID, speakerID, speakerName, uuid, filteredAudio, sampleRate)
+	sample, err := h.manager.RegisterSpeaker(uid, agentID, speakerID, speakerName, uuid, filteredAudio, sampleRate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to register speaker: %v", err),
-		})
+		var duplicateErr *DuplicateSpeakerError
+		if errors.As(err, &duplicateErr) {
+			respondError(c, err, gin.H{
+				"conflicting_speaker_id": duplicateErr.Match.SpeakerID,
+				"duplicate":              duplicateErr.Match,
+			})
+			return
+		}
+		respondError(c, fmt.Errorf("failed to register speaker: %w", err))
 		return
 	}
 
-	// 保存音频文件（异步保存，不阻塞响应）
+	// 归档注册音频（异步保存，不阻塞响应）
 	go func() {
-		if err := saveRegisterAudioToWAV(filteredAudio, sampleRate, uid, agentID); err != nil {
+		record := &AudioRecord{
+			Kind:        AudioKindRegister,
+			UID:         uid,
+			AgentID:     agentID,
+			SpeakerID:   speakerID,
+			SpeakerUUID: uuid,
+			SampleIndex: &sample.SampleIndex,
+			PointID:     sample.PointID,
+		}
+		if err := h.manager.ArchiveAudio(record, filteredAudio, sampleRate); err != nil {
 			logger.Warnf("Failed to save register audio file: %v", err)
 		} else {
 			logger.Infof("Register audio file saved successfully, samples: %d", len(filteredAudio))
 		}
 	}()
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message":      "Speaker registered successfully",
 		"uid":          uid,
 		"agent_id":     agentID,
 		"speaker_id":   speakerID,
 		"speaker_name": speakerName,
 		"uuid":         uuid,
-	})
+	}
+	entry.Detail = map[string]interface{}{"uuid": uuid, "point_id": sample.PointID, "sample_index": sample.SampleIndex}
+	// 重复声纹检测为 warn 模式时返回冲突的说话人
+	if sample.Duplicate != nil {
+		response["conflicting_speaker_id"] = sample.Duplicate.SpeakerID
+		response["duplicate"] = sample.Duplicate
+		entry.Detail["conflicting_speaker_id"] = sample.Duplicate.SpeakerID
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // IdentifySpeaker 识别声纹
@@ -229,6 +381,9 @@ func (h *Handler) IdentifySpeaker(c *gin.Context) {
 	// 获取 Agent ID（可选）
 	agentID := getAgentIDFromRequest(c)
 
+	entry := &AuditEntry{Action: AuditActionIdentify, UID: uid, AgentID: agentID}
+	defer h.audit(c, entry)
+
 	// 获取 speaker_id 参数（可选）
 	speakerID := c.Query("speaker_id")
 	if speakerID == "" {
@@ -244,9 +399,7 @@ func (h *Handler) IdentifySpeaker(c *gin.Context) {
 	// 获取音频文件
 	file, header, err := c.Request.FormFile("audio")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "audio file is required",
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "audio file is required")
 		return
 	}
 	defer file.Close()
@@ -254,9 +407,7 @@ func (h *Handler) IdentifySpeaker(c *gin.Context) {
 	// 解析音频数据
 	audioData, sampleRate, err := h.parseAudioFile(file, header)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("failed to parse audio file: %v", err),
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("failed to parse audio file: %v", err))
 		return
 	}
 
@@ -272,31 +423,35 @@ func (h *Handler) IdentifySpeaker(c *gin.Context) {
 		}
 	}
 
+	// 获取 top_k 参数（可选，默认返回 1 个候选）
+	topK := getTopKFromRequest(c)
+
 	// 识别声纹（如果提供了阈值则使用，否则使用默认值）
 	var result *IdentifyResult
 	if threshold > 0 {
-		result, err = h.manager.IdentifySpeaker(uid, agentID, speakerID, speakerName, audioData, sampleRate, threshold)
+		result, err = h.manager.IdentifySpeaker(uid, agentID, speakerID, speakerName, audioData, sampleRate, topK, threshold)
 	} else {
-		result, err = h.manager.IdentifySpeaker(uid, agentID, speakerID, speakerName, audioData, sampleRate)
+		result, err = h.manager.IdentifySpeaker(uid, agentID, speakerID, speakerName, audioData, sampleRate, topK)
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to identify speaker: %v", err),
-		})
+		respondError(c, fmt.Errorf("failed to identify speaker: %w", err))
 		return
 	}
 
+	auditIdentifyResult(entry, result)
 	c.JSON(http.StatusOK, result)
 }
 
 // VerifySpeaker 验证声纹
 func (h *Handler) VerifySpeaker(c *gin.Context) {
+	entry := &AuditEntry{Action: AuditActionVerify}
+	defer h.audit(c, entry)
+
 	// 获取 UID
 	uid := getUIDFromRequest(c)
+	entry.UID = uid
 	if uid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "uid is required (X-User-ID header, uid query param, or uid form field)",
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
 		return
 	}
 
@@ -304,19 +459,17 @@ func (h *Handler) VerifySpeaker(c *gin.Context) {
 	agentID := getAgentIDFromRequest(c)
 
 	speakerID := c.Param("speaker_id")
+	entry.AgentID = agentID
+	entry.SpeakerID = speakerID
 	if speakerID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "speaker_id is required",
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "speaker_id is required")
 		return
 	}
 
 	// 获取音频文件
 	file, header, err := c.Request.FormFile("audio")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "audio file is required",
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "audio file is required")
 		return
 	}
 	defer file.Close()
@@ -324,148 +477,862 @@ func (h *Handler) VerifySpeaker(c *gin.Context) {
 	// 解析音频数据
 	audioData, sampleRate, err := h.parseAudioFile(file, header)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": fmt.Sprintf("failed to parse audio file: %v", err),
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("failed to parse audio file: %v", err))
 		return
 	}
 
-	// 验证声纹
-	result, err := h.manager.VerifySpeaker(uid, agentID, speakerID, audioData, sampleRate)
+	// 获取 aggregation 参数（可选，max、mean 或 centroid，默认 max）
+	aggregation := c.Query("aggregation")
+	if aggregation == "" {
+		aggregation = c.PostForm("aggregation")
+	}
+
+	threshold, ok := getThresholdFromRequest(c)
+	if !ok {
+		return
+	}
+
+	// 验证声纹（threshold 可选；top_k 可选，提供时额外返回候选说话人排名）
+	result, err := h.manager.VerifySpeaker(uid, agentID, speakerID, audioData, sampleRate, VerifyOptions{
+		Threshold:   threshold,
+		Aggregation: aggregation,
+		TopK:        getTopKFromRequest(c),
+	})
 	if err != nil {
-		if strings.Contains(err.Error(), "belongs to different uid") {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": err.Error(),
-			})
+		respondError(c, fmt.Errorf("failed to verify speaker: %w", err))
+		return
+	}
+
+	auditVerifyResult(entry, result)
+	c.JSON(http.StatusOK, result)
+}
+
+// CompareSpeakers 比对两段音频是否为同一说话人，不读写数据库
+// 表单字段 audio_a、audio_b 为两段音频；threshold 可选，默认使用配置的余弦相似度阈值
+func (h *Handler) CompareSpeakers(c *gin.Context) {
+	audioA, sampleRateA, ok := h.readAudioField(c, "audio_a")
+	if !ok {
+		return
+	}
+	audioB, sampleRateB, ok := h.readAudioField(c, "audio_b")
+	if !ok {
+		return
+	}
+
+	threshold, ok := getThresholdFromRequest(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.manager.CompareAudio(audioA, sampleRateA, audioB, sampleRateB, threshold)
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to compare speakers: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// EmbedAudio 提取上传音频的 L2 归一化声纹向量，不写入数据库
+// 表单字段 audio 为音频文件；window_seconds 可选，提供时额外返回每 N 秒一个窗口的声纹向量
+func (h *Handler) EmbedAudio(c *gin.Context) {
+	audioData, sampleRate, ok := h.readAudioField(c, "audio")
+	if !ok {
+		return
+	}
+
+	var windowSeconds float64
+	windowStr := c.Query("window_seconds")
+	if windowStr == "" {
+		windowStr = c.PostForm("window_seconds")
+	}
+	if windowStr != "" {
+		v, err := strconv.ParseFloat(windowStr, 64)
+		if err != nil || v <= 0 {
+			errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "invalid window_seconds")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to verify speaker: %v", err),
-		})
+		windowSeconds = v
+	}
+
+	result, err := h.manager.EmbedAudio(audioData, sampleRate, windowSeconds)
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to extract embedding: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// readAudioField 读取并解析表单中的音频文件，失败时直接返回 400
+func (h *Handler) readAudioField(c *gin.Context, field string) ([]float32, int, bool) {
+	file, header, err := c.Request.FormFile(field)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("%s file is required", field))
+		return nil, 0, false
+	}
+	defer file.Close()
+
+	audioData, sampleRate, err := h.parseAudioFile(file, header)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("failed to parse %s: %v", field, err))
+		return nil, 0, false
+	}
+	return audioData, sampleRate, true
+}
+
+// IssueChallenge 为声称的说话人下发一次性挑战（随机数字串）
+func (h *Handler) IssueChallenge(c *gin.Context) {
+	uid := getUIDFromRequest(c)
+	if uid == "" {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
+		return
+	}
+	agentID := getAgentIDFromRequest(c)
+
+	speakerID := c.Query("speaker_id")
+	if speakerID == "" {
+		speakerID = c.PostForm("speaker_id")
+	}
+	if speakerID == "" {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "speaker_id is required")
+		return
+	}
+
+	challenge, err := h.manager.IssueChallenge(uid, agentID, speakerID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, challenge)
+}
+
+// VerifyChallenge 校验挑战应答，表单字段 audio 为朗读提示后的录音
+// threshold、aggregation 参数与声纹验证接口相同
+func (h *Handler) VerifyChallenge(c *gin.Context) {
+	entry := &AuditEntry{Action: AuditActionVerifyChallenge, Detail: map[string]interface{}{"challenge_id": c.Param("challenge_id")}}
+	defer h.audit(c, entry)
+
+	uid := getUIDFromRequest(c)
+	entry.UID = uid
+	if uid == "" {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
+		return
+	}
+	agentID := getAgentIDFromRequest(c)
+	entry.AgentID = agentID
+
+	audioData, sampleRate, ok := h.readAudioField(c, "audio")
+	if !ok {
 		return
 	}
 
+	aggregation := c.Query("aggregation")
+	if aggregation == "" {
+		aggregation = c.PostForm("aggregation")
+	}
+
+	threshold, ok := getThresholdFromRequest(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.manager.VerifyChallenge(c.Param("challenge_id"), uid, agentID, audioData, sampleRate, VerifyOptions{
+		Threshold:   threshold,
+		Aggregation: aggregation,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 文本和声纹都通过才记为通过，分数记录声纹分数
+	auditVerifyResult(entry, result.Voice)
+	entry.Detail["text_matched"] = result.TextMatched
+	entry.Detail["char_error_rate"] = result.CharErrorRate
+	if !result.Verified {
+		entry.Decision = AuditDecisionReject
+	}
 	c.JSON(http.StatusOK, result)
 }
 
 // GetAllSpeakers 获取所有说话人
+// 可选查询参数：
+// - name_prefix: 名称前缀过滤
+// - created_after / created_before: 创建时间范围（Unix 秒或 RFC3339）
+// - min_samples: 最少样本数
+// - sort: created_at（默认）、updated_at、name、sample_count、id；order: asc（默认）或 desc
+// - limit: 每页数量（不传则返回全部）；cursor: 上一页返回的 next_cursor
+// 响应中的 total 为满足过滤条件的说话人总数，按名称前缀或创建时间过滤时为 null
 func (h *Handler) GetAllSpeakers(c *gin.Context) {
 	// 获取 UID
 	uid := getUIDFromRequest(c)
 	if uid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "uid is required (X-User-ID header, uid query param, or uid form field)",
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
 		return
 	}
 
 	// 获取 Agent ID（可选）
 	agentID := getAgentIDFromRequest(c)
 
-	speakers := h.manager.GetAllSpeakers(uid, agentID)
+	opts := SpeakerListOptions{
+		NamePrefix: c.Query("name_prefix"),
+		SortBy:     c.Query("sort"),
+		Cursor:     c.Query("cursor"),
+	}
+
+	switch order := c.Query("order"); order {
+	case "", "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("invalid order: %s", order))
+		return
+	}
+
+	var err error
+	if opts.CreatedAfter, err = parseTimeParam(c.Query("created_after")); err != nil {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("invalid created_after: %v", err))
+		return
+	}
+	if opts.CreatedBefore, err = parseTimeParam(c.Query("created_before")); err != nil {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("invalid created_before: %v", err))
+		return
+	}
+	if s := c.Query("min_samples"); s != "" {
+		if opts.MinSamples, err = parseInt(s); err != nil {
+			errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("invalid min_samples: %s", s))
+			return
+		}
+	}
+	if s := c.Query("limit"); s != "" {
+		if opts.Limit, err = parseInt(s); err != nil || opts.Limit <= 0 {
+			errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("invalid limit: %s", s))
+			return
+		}
+	}
+
+	page, err := h.manager.ListSpeakers(uid, agentID, opts)
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to list speakers: %w", err))
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"uid":      uid,
-		"agent_id": agentID,
-		"speakers": speakers,
-		"total":    len(speakers),
+		"uid":         uid,
+		"agent_id":    agentID,
+		"speakers":    page.Speakers,
+		"total":       page.Total,
+		"next_cursor": page.NextCursor,
+		"has_more":    page.HasMore,
 	})
 }
 
+// parseTimeParam 解析时间参数（Unix 秒或 RFC3339），为空时返回零值
+func parseTimeParam(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(ts, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
 // DeleteSpeaker 删除说话人
 // 支持两种方式：
 // 1. 通过查询参数 uuid 删除：DELETE /api/v1/speaker?uuid=xxx
 // 2. 通过路径参数 speaker_id 删除：DELETE /api/v1/speaker/:speaker_id（用于删除整个声纹组）
 func (h *Handler) DeleteSpeaker(c *gin.Context) {
+	entry := &AuditEntry{Action: AuditActionDelete}
+	defer h.audit(c, entry)
+
 	// 获取 UID
 	uid := getUIDFromRequest(c)
+	entry.UID = uid
 	if uid == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "uid is required (X-User-ID header, uid query param, or uid form field)",
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
 		return
 	}
 
 	// 获取 Agent ID（可选）
 	agentID := getAgentIDFromRequest(c)
 
+	entry.AgentID = agentID
+
 	// 优先使用 uuid 查询参数
 	uuid := c.Query("uuid")
 	if uuid != "" {
+		entry.Detail = map[string]interface{}{"uuid": uuid}
 		// 通过 UUID 删除
 		err := h.manager.DeleteSpeakerByUUID(uid, agentID, uuid)
 		if err != nil {
-			if strings.Contains(err.Error(), "not found") {
-				c.JSON(http.StatusNotFound, gin.H{
-					"error": err.Error(),
-				})
-				return
-			}
-			if strings.Contains(err.Error(), "belongs to different uid") {
-				c.JSON(http.StatusForbidden, gin.H{
-					"error": err.Error(),
-				})
-				return
-			}
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("failed to delete speaker: %v", err),
+			respondError(c, fmt.Errorf("failed to delete speaker: %w", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":  "Speaker deleted successfully",
+			"uid":      uid,
+			"agent_id": agentID,
+			"uuid":     uuid,
+		})
+		return
+	}
+
+	// 通过路径参数 speaker_id 删除（speaker_id 就是组名称）
+	speakerID := c.Param("speaker_id")
+	if speakerID == "" {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uuid or speaker_id is required")
+		return
+	}
+
+	entry.SpeakerID = speakerID
+	err := h.manager.DeleteSpeaker(uid, agentID, speakerID)
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to delete speaker: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Speaker deleted successfully",
+		"uid":        uid,
+		"agent_id":   agentID,
+		"speaker_id": speakerID,
+	})
+}
+
+// RenameSpeaker 修改说话人名称
+// 请求体（JSON 或表单）：speaker_name
+func (h *Handler) RenameSpeaker(c *gin.Context) {
+	entry := &AuditEntry{Action: AuditActionRename, SpeakerID: c.Param("speaker_id")}
+	defer h.audit(c, entry)
+
+	// 获取 UID
+	uid := getUIDFromRequest(c)
+	entry.UID = uid
+	if uid == "" {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
+		return
+	}
+
+	// 获取 Agent ID（可选）
+	agentID := getAgentIDFromRequest(c)
+	entry.AgentID = agentID
+	speakerID := c.Param("speaker_id")
+
+	var req struct {
+		SpeakerName string `json:"speaker_name" form:"speaker_name" binding:"required"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "speaker_name is required")
+		return
+	}
+	entry.Detail = map[string]interface{}{"speaker_name": req.SpeakerName}
+
+	if err := h.manager.RenameSpeaker(uid, agentID, speakerID, req.SpeakerName); err != nil {
+		respondError(c, fmt.Errorf("failed to rename speaker: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Speaker renamed successfully",
+		"uid":          uid,
+		"agent_id":     agentID,
+		"speaker_id":   speakerID,
+		"speaker_name": req.SpeakerName,
+	})
+}
+
+// MergeSpeaker 将请求体中的 source_speaker_id（B）并入路径中的说话人（A）
+// 请求体（JSON）：source_speaker_id、min_similarity（可选）、force（可选）
+func (h *Handler) MergeSpeaker(c *gin.Context) {
+	entry := &AuditEntry{Action: AuditActionMerge, SpeakerID: c.Param("speaker_id")}
+	defer h.audit(c, entry)
+
+	uid := getUIDFromRequest(c)
+	entry.UID = uid
+	if uid == "" {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
+		return
+	}
+	agentID := getAgentIDFromRequest(c)
+	entry.AgentID = agentID
+
+	var opts MergeOptions
+	if err := c.ShouldBindJSON(&opts); err != nil {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, err.Error())
+		return
+	}
+	opts.TargetSpeakerID = c.Param("speaker_id")
+	entry.Detail = map[string]interface{}{"source_speaker_id": opts.SourceSpeakerID, "force": opts.Force}
+
+	report, err := h.manager.MergeSpeakers(uid, agentID, opts)
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to merge speakers: %w", err))
+		return
+	}
+
+	entry.Score = &report.CentroidSimilarity
+	entry.Detail["samples_moved"] = report.SamplesMoved
+	entry.Detail["sample_indices"] = report.SampleIndices
+	c.JSON(http.StatusOK, report)
+}
+
+// SplitSpeaker 将路径中说话人的部分样本移到新的说话人下
+// 请求体（JSON）：sample_indices、new_speaker_id、new_speaker_name（可选）、uuid（可选）
+func (h *Handler) SplitSpeaker(c *gin.Context) {
+	entry := &AuditEntry{Action: AuditActionSplit, SpeakerID: c.Param("speaker_id")}
+	defer h.audit(c, entry)
+
+	uid := getUIDFromRequest(c)
+	entry.UID = uid
+	if uid == "" {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
+		return
+	}
+	agentID := getAgentIDFromRequest(c)
+	entry.AgentID = agentID
+
+	var opts SplitOptions
+	if err := c.ShouldBindJSON(&opts); err != nil {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, err.Error())
+		return
+	}
+	entry.Detail = map[string]interface{}{"new_speaker_id": opts.NewSpeakerID, "sample_indices": opts.SampleIndices}
+
+	report, err := h.manager.SplitSpeaker(uid, agentID, c.Param("speaker_id"), opts)
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to split speaker: %w", err))
+		return
+	}
+
+	entry.Score = &report.CentroidSimilarity
+	entry.Detail["uuid"] = report.UUID
+	entry.Detail["samples_moved"] = report.SamplesMoved
+	c.JSON(http.StatusOK, report)
+}
+
+// ListSpeakerSamples 获取说话人的样本列表
+func (h *Handler) ListSpeakerSamples(c *gin.Context) {
+	// 获取 UID
+	uid := getUIDFromRequest(c)
+	if uid == "" {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
+		return
+	}
+
+	// 获取 Agent ID（可选）
+	agentID := getAgentIDFromRequest(c)
+	speakerID := c.Param("speaker_id")
+
+	samples, err := h.manager.ListSpeakerSamples(uid, agentID, speakerID)
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to list samples: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"uid":        uid,
+		"agent_id":   agentID,
+		"speaker_id": speakerID,
+		"samples":    samples,
+		"total":      len(samples),
+	})
+}
+
+// AddSpeakerSample 为已注册的说话人追加样本（multipart 表单字段 audio）
+func (h *Handler) AddSpeakerSample(c *gin.Context) {
+	entry := &AuditEntry{Action: AuditActionAddSample, SpeakerID: c.Param("speaker_id")}
+	defer h.audit(c, entry)
+
+	// 获取 UID
+	uid := getUIDFromRequest(c)
+	entry.UID = uid
+	if uid == "" {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
+		return
+	}
+
+	// 获取 Agent ID（可选）
+	agentID := getAgentIDFromRequest(c)
+	speakerID := c.Param("speaker_id")
+
+	// 获取音频文件
+	file, header, err := c.Request.FormFile("audio")
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "audio file is required")
+		return
+	}
+	defer file.Close()
+
+	// 解析音频数据
+	audioData, sampleRate, err := h.parseAudioFile(file, header)
+	if err != nil {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("failed to parse audio file: %v", err))
+		return
+	}
+
+	// 使用VAD过滤静音，保留前后100ms的静音
+	filteredAudio, err := h.manager.FilterSilenceWithVADKeepEdges(audioData, sampleRate)
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to filter silence: %w", err))
+		return
+	}
+
+	entry.AgentID = agentID
+	sample, err := h.manager.AddSpeakerSample(uid, agentID, speakerID, filteredAudio, sampleRate)
+	if err != nil {
+		var duplicateErr *DuplicateSpeakerError
+		if errors.As(err, &duplicateErr) {
+			respondError(c, err, gin.H{
+				"conflicting_speaker_id": duplicateErr.Match.SpeakerID,
+				"duplicate":              duplicateErr.Match,
 			})
 			return
 		}
+		respondError(c, fmt.Errorf("failed to add sample: %w", err))
+		return
+	}
+
+	entry.Detail = map[string]interface{}{"uuid": sample.UUID, "point_id": sample.PointID, "sample_index": sample.SampleIndex}
+
+	// 归档注册音频（异步保存，不阻塞响应）
+	go func() {
+		record := &AudioRecord{
+			Kind:        AudioKindRegister,
+			UID:         uid,
+			AgentID:     sample.AgentID,
+			SpeakerID:   speakerID,
+			SpeakerUUID: sample.UUID,
+			SampleIndex: &sample.SampleIndex,
+			PointID:     sample.PointID,
+		}
+		if err := h.manager.ArchiveAudio(record, filteredAudio, sampleRate); err != nil {
+			logger.Warnf("Failed to save register audio file: %v", err)
+		}
+	}()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Sample added successfully",
+		"uid":     uid,
+		"sample":  sample,
+	})
+}
+
+// DeleteSpeakerSample 删除说话人的单个样本
+func (h *Handler) DeleteSpeakerSample(c *gin.Context) {
+	entry := &AuditEntry{Action: AuditActionDeleteSample, SpeakerID: c.Param("speaker_id")}
+	defer h.audit(c, entry)
+
+	// 获取 UID
+	uid := getUIDFromRequest(c)
+	entry.UID = uid
+	if uid == "" {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
+		return
+	}
+
+	// 获取 Agent ID（可选）
+	agentID := getAgentIDFromRequest(c)
+	speakerID := c.Param("speaker_id")
+
+	sampleIndex, err := parseInt(c.Param("sample_index"))
+	if err != nil || sampleIndex < 0 {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "invalid sample_index")
+		return
+	}
+
+	entry.AgentID = agentID
+	entry.Detail = map[string]interface{}{"sample_index": sampleIndex}
+	if err := h.manager.DeleteSpeakerSample(uid, agentID, speakerID, sampleIndex); err != nil {
+		respondError(c, fmt.Errorf("failed to delete sample: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Sample deleted successfully",
+		"uid":          uid,
+		"agent_id":     agentID,
+		"speaker_id":   speakerID,
+		"sample_index": sampleIndex,
+	})
+}
+
+// GetStats 获取数据库统计信息
+func (h *Handler) GetStats(c *gin.Context) {
+	// UID 是可选的，如果不提供则返回全局统计
+	uid := getUIDFromRequest(c)
+	// Agent ID 是可选的
+	agentID := getAgentIDFromRequest(c)
+	stats := h.manager.GetStats(uid, agentID)
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetConfusionReport 计算 uid/agent_id 下说话人之间的可混淆性报告
+// 查询参数：warn_level（默认当前余弦相似度阈值）、include_matrix=true 返回完整相似度矩阵
+func (h *Handler) GetConfusionReport(c *gin.Context) {
+	uid := getUIDFromRequest(c)
+	if uid == "" {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
+		return
+	}
+	agentID := getAgentIDFromRequest(c)
+
+	var warnLevel float32
+	if s := c.Query("warn_level"); s != "" {
+		v, err := strconv.ParseFloat(s, 32)
+		if err != nil || v <= 0 || v > 1 {
+			errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "invalid warn_level, must be in (0, 1]")
+			return
+		}
+		warnLevel = float32(v)
+	}
+
+	report, err := h.manager.ConfusionReport(uid, agentID, warnLevel, c.Query("include_matrix") == "true")
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to compute confusion report: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// EraseUser 删除 uid（可选 agent_id）的全部声纹数据和保存的音频，返回删除回执
+func (h *Handler) EraseUser(c *gin.Context) {
+	entry := &AuditEntry{Action: AuditActionErase}
+	defer h.audit(c, entry)
+
+	uid := getUIDFromRequest(c)
+	if uid == "" {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
+		return
+	}
+	agentID := getAgentIDFromRequest(c)
+	entry.UID = uid
+	entry.AgentID = agentID
+
+	receipt, err := h.manager.EraseUser(uid, agentID)
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to erase user data: %w", err))
+		return
+	}
+
+	// 部分删除失败时仍返回回执，状态码提示需要重试
+	status := http.StatusOK
+	if !receipt.Complete {
+		status = http.StatusInternalServerError
+		entry.Error = strings.Join(receipt.Errors, "; ")
+	}
+	entry.Detail = map[string]interface{}{
+		"receipt_id":          receipt.ReceiptID,
+		"speaker_ids":         receipt.SpeakerIDs,
+		"points_deleted":      receipt.PointsDeleted,
+		"audio_files_deleted": len(receipt.AudioFilesDeleted),
+	}
+	if len(receipt.UnattributedAudio) > 0 {
+		entry.Detail["unattributed_audio"] = len(receipt.UnattributedAudio)
+	}
+	c.JSON(status, receipt)
+}
+
+// ListArchivedAudio 查询 uid（可选 agent_id）的归档音频元数据
+func (h *Handler) ListArchivedAudio(c *gin.Context) {
+	uid := getUIDFromRequest(c)
+	if uid == "" {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "uid is required (X-User-ID header, uid query param, or uid form field)")
+		return
+	}
+	agentID := getAgentIDFromRequest(c)
+
+	records, err := h.manager.ListArchivedAudio(uid, agentID)
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to list archived audio: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"uid":      uid,
+		"agent_id": agentID,
+		"count":    len(records),
+		"files":    records,
+	})
+}
+
+// QueryAudit 查询审计记录，按时间倒序返回
+// 查询参数：uid、agent_id、speaker_id、action、decision、request_id、since/until（Unix 秒或 RFC3339）、limit（默认 100，最大 1000）
+func (h *Handler) QueryAudit(c *gin.Context) {
+	filter := AuditFilter{
+		UID:       c.Query("uid"),
+		AgentID:   c.Query("agent_id"),
+		SpeakerID: c.Query("speaker_id"),
+		Action:    c.Query("action"),
+		Decision:  c.Query("decision"),
+		RequestID: c.Query("request_id"),
+		Limit:     100,
+	}
+	if s := c.Query("since"); s != "" {
+		t, err := parseTimeParam(s)
+		if err != nil {
+			errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("invalid since: %v", err))
+			return
+		}
+		filter.Since = t
+	}
+	if s := c.Query("until"); s != "" {
+		t, err := parseTimeParam(s)
+		if err != nil {
+			errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("invalid until: %v", err))
+			return
+		}
+		filter.Until = t
+	}
+	if s := c.Query("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 || limit > 1000 {
+			errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "invalid limit, must be in [1, 1000]")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := h.manager.QueryAudit(filter)
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to query audit log: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count":   len(entries),
+		"entries": entries,
+	})
+}
+
+// auditIdentifyResult 将识别结果写入审计记录
+func auditIdentifyResult(entry *AuditEntry, result *IdentifyResult) {
+	entry.SpeakerID = result.SpeakerID
+	entry.Score = &result.Confidence
+	entry.Threshold = &result.Threshold
+	entry.Decision = AuditDecisionReject
+	if result.Identified {
+		entry.Decision = AuditDecisionAccept
+	}
+	if result.AutoEnrolled != nil {
+		if entry.Detail == nil {
+			entry.Detail = map[string]interface{}{}
+		}
+		entry.Detail["auto_enrolled"] = result.AutoEnrolled
+	}
+}
+
+// auditVerifyResult 将验证结果写入审计记录
+func auditVerifyResult(entry *AuditEntry, result *VerifyResult) {
+	entry.SpeakerID = result.SpeakerID
+	entry.Score = &result.Confidence
+	entry.Threshold = &result.Threshold
+	entry.Decision = AuditDecisionReject
+	if result.Verified {
+		entry.Decision = AuditDecisionAccept
+	}
+}
+
+// CalibrateThreshold 使用服务器上的标注数据目录评估识别效果并推荐阈值
+// 请求体为 CalibrationOptions，dir 必须位于 speaker.calibration.root_dir 内（相对路径基于该目录）；
+// 额外字段 apply=true 时通过配置热加载管理器写回推荐阈值
+func (h *Handler) CalibrateThreshold(c *gin.Context) {
+	var req struct {
+		CalibrationOptions
+		Apply bool `json:"apply"`
+	}
 
-		c.JSON(http.StatusOK, gin.H{
-			"message":  "Speaker deleted successfully",
-			"uid":      uid,
-			"agent_id": agentID,
-			"uuid":     uuid,
-		})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, err.Error())
 		return
 	}
 
-	// 通过路径参数 speaker_id 删除（speaker_id 就是组名称）
-	speakerID := c.Param("speaker_id")
-	if speakerID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "uuid or speaker_id is required",
-		})
+	if req.Dir == "" {
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "dir is required")
 		return
 	}
+	dir, err := h.manager.ResolveCalibrationDir(req.Dir)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	req.Dir = dir
 
-	err := h.manager.DeleteSpeaker(uid, agentID, speakerID)
+	report, err := h.manager.EvaluateTrials(req.CalibrationOptions)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": err.Error(),
-			})
-			return
-		}
-		if strings.Contains(err.Error(), "belongs to different uid") {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": err.Error(),
-			})
+		respondError(c, fmt.Errorf("failed to evaluate trials: %w", err))
+		return
+	}
+
+	if req.Apply {
+		if err := ApplyCalibration(h.configWriter, report); err != nil {
+			respondError(c, fmt.Errorf("failed to apply threshold: %w", err), gin.H{"report": report})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("failed to delete speaker: %v", err),
-		})
-		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message":    "Speaker deleted successfully",
-		"uid":        uid,
-		"agent_id":   agentID,
-		"speaker_id": speakerID,
-	})
+	c.JSON(http.StatusOK, report)
 }
 
-// GetStats 获取数据库统计信息
-func (h *Handler) GetStats(c *gin.Context) {
-	// UID 是可选的，如果不提供则返回全局统计
+// ExportSpeakers 导出说话人数据（gzip 压缩的 JSON Lines）
+// uid、agent_id 均可选，不传 uid 时导出整个 Collection
+func (h *Handler) ExportSpeakers(c *gin.Context) {
 	uid := getUIDFromRequest(c)
-	// Agent ID 是可选的
 	agentID := getAgentIDFromRequest(c)
-	stats := h.manager.GetStats(uid, agentID)
-	c.JSON(http.StatusOK, stats)
+
+	// 直接写入响应，不在内存中缓冲整个导出文件；开始写入前的错误仍以 JSON 返回
+	started := false
+	_, err := h.manager.Export(c.Writer, uid, agentID, func(header *ArchiveHeader) {
+		started = true
+		filename := fmt.Sprintf("speakers_%s.jsonl.gz", header.ExportedAt.Format("20060102_150405"))
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Header("X-Export-Count", strconv.Itoa(header.Count))
+		c.Header("Content-Type", "application/gzip")
+		c.Status(http.StatusOK)
+	})
+	if err != nil {
+		if !started {
+			respondError(c, fmt.Errorf("failed to export speakers: %w", err))
+			return
+		}
+		// 响应已开始，gzip 尾部未写入，客户端解压时会发现文件不完整
+		logger.Errorf("Failed to export speakers: %v", err)
+		c.Abort()
+	}
+}
+
+// ImportSpeakers 导入说话人数据
+// 导出文件通过 multipart 表单字段 archive 上传，或直接作为请求体上传
+// 查询参数：mode=merge|replace（默认 merge）、dry_run=true、allow_model_mismatch=true
+func (h *Handler) ImportSpeakers(c *gin.Context) {
+	opts := ImportOptions{
+		Mode:               c.Query("mode"),
+		DryRun:             c.Query("dry_run") == "true",
+		AllowModelMismatch: c.Query("allow_model_mismatch") == "true",
+	}
+
+	var archive io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, _, err := c.Request.FormFile("archive")
+		if err != nil {
+			errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, "archive file is required")
+			return
+		}
+		defer file.Close()
+		archive = file
+	}
+
+	report, err := h.manager.Import(archive, opts)
+	if err != nil {
+		respondError(c, fmt.Errorf("failed to import speakers: %w", err), gin.H{"report": report})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
 }
 
 // parseAudioFile 解析音频文件
@@ -473,13 +1340,18 @@ func (h *Handler) parseAudioFile(file multipart.File, header *multipart.FileHead
 	// 检查文件类型
 	filename := strings.ToLower(header.Filename)
 	if !strings.HasSuffix(filename, ".wav") {
-		return nil, 0, fmt.Errorf("only WAV files are supported")
+		return nil, 0, newError(ErrInvalidArgument, "only WAV files are supported")
 	}
 
+	return decodeWAV(file)
+}
+
+// decodeWAV 解码 WAV 数据为单声道 float32 采样（立体声取平均值）
+func decodeWAV(r io.ReadSeeker) ([]float32, int, error) {
 	// 读取WAV文件
-	decoder := wav.NewDecoder(file)
+	decoder := wav.NewDecoder(r)
 	if !decoder.IsValidFile() {
-		return nil, 0, fmt.Errorf("invalid WAV file")
+		return nil, 0, newError(ErrInvalidArgument, "invalid WAV file")
 	}
 
 	// 获取音频格式信息
@@ -488,13 +1360,13 @@ func (h *Handler) parseAudioFile(file multipart.File, header *multipart.FileHead
 
 	// 只支持单声道或立体声
 	if numChannels > 2 {
-		return nil, 0, fmt.Errorf("unsupported number of channels: %d", numChannels)
+		return nil, 0, newError(ErrInvalidArgument, "unsupported number of channels: %d", numChannels)
 	}
 
 	// 读取音频数据
 	buffer, err := decoder.FullPCMBuffer()
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to decode audio: %v", err)
+		return nil, 0, fmt.Errorf("failed to decode audio: %w", err)
 	}
 
 	// 转换为float32格式
@@ -528,18 +1400,14 @@ func (h *Handler) RegisterSpeakerBase64(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, err.Error())
 		return
 	}
 
 	// 这里可以添加Base64解码和音频处理逻辑
 	// 为简化示例，暂时跳过具体实现
 
-	c.JSON(http.StatusNotImplemented, gin.H{
-		"error": "Base64 API not implemented yet",
-	})
+	errorResponse(c, http.StatusNotImplemented, CodeNotImplemented, "Base64 API not implemented yet")
 }
 
 // IdentifySpeakerBase64 使用Base64编码的音频数据识别声纹
@@ -550,18 +1418,14 @@ func (h *Handler) IdentifySpeakerBase64(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, err.Error())
 		return
 	}
 
 	// 这里可以添加Base64解码和音频处理逻辑
 	// 为简化示例，暂时跳过具体实现
 
-	c.JSON(http.StatusNotImplemented, gin.H{
-		"error": "Base64 API not implemented yet",
-	})
+	errorResponse(c, http.StatusNotImplemented, CodeNotImplemented, "Base64 API not implemented yet")
 }
 
 // WebSocketUpgrader WebSocket升级器
@@ -613,6 +1477,9 @@ func (h *Handler) IdentifySpeakerWebSocket(c *gin.Context) {
 		}
 	}
 
+	// 获取 top_k 参数（可选）
+	topK := getTopKFromRequest(c)
+
 	// 获取 UID（从查询参数或请求头，可选）
 	uid := getUIDFromRequest(c)
 
@@ -625,13 +1492,25 @@ func (h *Handler) IdentifySpeakerWebSocket(c *gin.Context) {
 	// 获取 speaker_name 参数（可选）
 	speakerName := c.Query("speaker_name")
 
+	// 每个识别轮次写一条审计记录，请求 ID 为连接的请求 ID，轮次由 round_id 区分
+	clientIP := c.ClientIP()
+	requestID := middleware.GetRequestID(c)
+	auditRound := func(entry *AuditEntry) {
+		entry.Action = AuditActionIdentifyWS
+		entry.UID = uid
+		entry.AgentID = agentID
+		entry.ClientIP = clientIP
+		entry.RequestID = requestID
+		h.manager.RecordAudit(entry)
+	}
+
 	// 创建流式识别器的辅助函数
 	createIdentifier := func() *StreamingIdentifier {
-		logger.Debugf("WebSocket: Creating streaming identifier for uid: %s, agent_id: %s, speaker_id: %s, speaker_name: %s, sample rate: %d Hz, threshold: %.4f", uid, agentID, speakerID, speakerName, sampleRate, threshold)
+		logger.Debugf("WebSocket: Creating streaming identifier for uid: %s, agent_id: %s, speaker_id: %s, speaker_name: %s, sample rate: %d Hz, threshold: %.4f, top_k: %d", uid, agentID, speakerID, speakerName, sampleRate, threshold, topK)
 		if threshold > 0 {
-			return h.manager.NewStreamingIdentifier(uid, agentID, speakerID, speakerName, sampleRate, threshold)
+			return h.manager.NewStreamingIdentifier(uid, agentID, speakerID, speakerName, sampleRate, topK, threshold)
 		}
-		return h.manager.NewStreamingIdentifier(uid, agentID, speakerID, speakerName, sampleRate)
+		return h.manager.NewStreamingIdentifier(uid, agentID, speakerID, speakerName, sampleRate, topK)
 	}
 
 	// 创建初始流式识别器
@@ -702,6 +1581,7 @@ func (h *Handler) IdentifySpeakerWebSocket(c *gin.Context) {
 			logger.Warnf("WebSocket: Message too large: %d bytes (max: %d)", len(message), wsConfig.MaxMessageSize)
 			conn.WriteJSON(map[string]interface{}{
 				"type":    "error",
+				"code":    CodeInvalidArgument,
 				"message": "message too large",
 			})
 			continue
@@ -724,13 +1604,21 @@ func (h *Handler) IdentifySpeakerWebSocket(c *gin.Context) {
 				case "finish":
 					// 完成当前轮次识别
 					roundCount++
+					roundID, _ := newRandomID()
 					logger.Debugf("WebSocket: Finish action received (round %d), total audio samples: %d, chunks: %d", roundCount, totalAudioSamples, audioChunkCount)
 					logger.Debugf("WebSocket: Calling FinishAndIdentify()...")
 					result, err := identifier.FinishAndIdentify()
 					if err != nil {
 						logger.Errorf("WebSocket: FinishAndIdentify failed: %v", err)
+						auditRound(&AuditEntry{
+							Decision: AuditDecisionError,
+							RoundID:  roundID,
+							Round:    roundCount,
+							Error:    err.Error(),
+						})
 						conn.WriteJSON(map[string]interface{}{
 							"type":    "error",
+							"code":    errorCodeOf(err),
 							"message": err.Error(),
 							"round":   roundCount,
 						})
@@ -743,15 +1631,29 @@ func (h *Handler) IdentifySpeakerWebSocket(c *gin.Context) {
 						continue
 					}
 
+					roundEntry := &AuditEntry{RoundID: roundID, Round: roundCount}
+					auditIdentifyResult(roundEntry, result)
+					auditRound(roundEntry)
+
 					// 如果启用了保存音频，保存音频文件
 					if saveAudioEnabled && len(audioBuffer) > 0 {
 						// 复制音频数据，避免在异步执行时数据被修改
 						audioDataCopy := make([]float32, len(audioBuffer))
 						copy(audioDataCopy, audioBuffer)
+						record := &AudioRecord{
+							Kind:       AudioKindIdentify,
+							UID:        uid,
+							AgentID:    agentID,
+							SpeakerID:  result.SpeakerID,
+							RoundID:    roundID,
+							Round:      roundCount,
+							Identified: &result.Identified,
+							Confidence: &result.Confidence,
+						}
 						currentRound := roundCount
 						go func() {
 							// 异步保存，不阻塞响应
-							if err := saveAudioToWAV(audioDataCopy, sampleRate, uid, agentID); err != nil {
+							if err := h.manager.ArchiveAudio(record, audioDataCopy, sampleRate); err != nil {
 								logger.Warnf("WebSocket: Failed to save audio file (round %d): %v", currentRound, err)
 							} else {
 								logger.Infof("WebSocket: Audio file saved successfully (round %d), samples: %d", currentRound, len(audioDataCopy))
@@ -826,6 +1728,7 @@ func (h *Handler) IdentifySpeakerWebSocket(c *gin.Context) {
 				logger.Warnf("WebSocket: Invalid audio data length: %d bytes (not divisible by 4)", len(message))
 				conn.WriteJSON(map[string]interface{}{
 					"type":    "error",
+					"code":    CodeInvalidArgument,
 					"message": "invalid audio data length",
 				})
 				continue
@@ -856,6 +1759,7 @@ func (h *Handler) IdentifySpeakerWebSocket(c *gin.Context) {
 				logger.Errorf("WebSocket: Failed to accept audio chunk #%d: %v", audioChunkCount+1, err)
 				conn.WriteJSON(map[string]interface{}{
 					"type":    "error",
+					"code":    errorCodeOf(err),
 					"message": err.Error(),
 				})
 				return
@@ -893,202 +1797,317 @@ func (h *Handler) IdentifySpeakerWebSocket(c *gin.Context) {
 		roundCount, audioChunkCount, totalAudioSamples, float64(totalAudioSamples)/float64(sampleRate))
 }
 
-// parseInt 解析整数（辅助函数）
-func parseInt(s string) (int, error) {
-	var result int
-	_, err := fmt.Sscanf(s, "%d", &result)
-	return result, err
-}
-
-// parseFloat32 解析浮点数（辅助函数）
-func parseFloat32(s string) (float32, error) {
-	var result float32
-	_, err := fmt.Sscanf(s, "%f", &result)
-	return result, err
-}
-
-// saveAudioToWAV 将音频数据保存为 WAV 文件
-func saveAudioToWAV(audioData []float32, sampleRate int, uid, agentID string) error {
-	if len(audioData) == 0 {
-		return fmt.Errorf("audio data is empty")
+// RegisterSpeakerWebSocket WebSocket流式注册声纹
+// 查询参数 uid、agent_id、speaker_id、speaker_name、uuid 必填（uid、agent_id 也可通过请求头传入），sample_rate 可选
+// 音频格式与流式识别相同（float32 小端序），每一轮经 VAD 裁剪和质量检查后作为一个样本写入，协议：
+// - 发送 {"action": "finish"} 完成当前轮次并保存样本，返回结果后自动重置状态，可继续录制下一段
+// - 发送 {"action": "cancel"} 丢弃当前轮次的音频，重置状态，准备下一轮
+// - 发送 {"action": "close"} 关闭连接
+func (h *Handler) RegisterSpeakerWebSocket(c *gin.Context) {
+	uid := getUIDFromRequest(c)
+	agentID := getAgentIDFromRequest(c)
+	speakerID := c.Query("speaker_id")
+	speakerName := c.Query("speaker_name")
+	uuid := c.Query("uuid")
+	for _, param := range []struct{ name, value string }{
+		{"uid", uid}, {"agent_id", agentID}, {"speaker_id", speakerID}, {"speaker_name", speakerName}, {"uuid", uuid},
+	} {
+		if param.value == "" {
+			errorResponse(c, http.StatusBadRequest, CodeInvalidArgument, fmt.Sprintf("%s is required", param.name))
+			return
+		}
 	}
 
-	// 确定保存目录
-	saveDir := config.GlobalConfig.Speaker.AudioSaveDir
-	if saveDir == "" {
-		// 如果未指定，使用 data_dir
-		saveDir = config.GlobalConfig.Speaker.DataDir
-	}
-	if saveDir == "" {
-		saveDir = "data/speaker"
+	// 升级为WebSocket连接
+	conn, err := WebSocketUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		logger.Errorf("WebSocket upgrade failed: %v", err)
+		return
 	}
+	defer conn.Close()
 
-	// 创建保存目录（如果不存在）
-	if err := os.MkdirAll(saveDir, 0755); err != nil {
-		return fmt.Errorf("failed to create save directory: %w", err)
-	}
+	logger.Infof("WebSocket connection established for speaker registration, uid: %s, agent_id: %s, speaker_id: %s", uid, agentID, speakerID)
 
-	// 生成文件名：时间戳_uid_agentid.wav
-	timestamp := time.Now().Format("20060102_150405")
-	var filename string
-	if uid != "" && agentID != "" {
-		filename = fmt.Sprintf("%s_%s_%s.wav", timestamp, uid, agentID)
-	} else if uid != "" {
-		filename = fmt.Sprintf("%s_%s.wav", timestamp, uid)
-	} else if agentID != "" {
-		filename = fmt.Sprintf("%s_%s.wav", timestamp, agentID)
-	} else {
-		filename = fmt.Sprintf("%s.wav", timestamp)
+	// 获取采样率参数（默认16000）
+	sampleRate := 16000
+	if sr := c.Query("sample_rate"); sr != "" {
+		if srInt, err := parseInt(sr); err == nil && srInt > 0 {
+			sampleRate = srInt
+		} else {
+			logger.Warnf("WebSocket: Invalid sample_rate parameter '%s', using default 16000", sr)
+		}
 	}
 
-	// 清理文件名中的非法字符
-	filename = strings.ReplaceAll(filename, "/", "_")
-	filename = strings.ReplaceAll(filename, "\\", "_")
-	filename = strings.ReplaceAll(filename, ":", "_")
+	// 每个注册轮次写一条审计记录，请求 ID 为连接的请求 ID，轮次由 round_id 区分
+	clientIP := c.ClientIP()
+	requestID := middleware.GetRequestID(c)
+	auditRound := func(entry *AuditEntry) {
+		entry.Action = AuditActionRegisterWS
+		entry.UID = uid
+		entry.AgentID = agentID
+		entry.SpeakerID = speakerID
+		entry.ClientIP = clientIP
+		entry.RequestID = requestID
+		h.manager.RecordAudit(entry)
+	}
 
-	filePath := filepath.Join(saveDir, filename)
+	createRegistrar := func() *StreamingRegistrar {
+		return h.manager.NewStreamingRegistrar(uid, agentID, speakerID, speakerName, uuid, sampleRate)
+	}
+	registrar := createRegistrar()
+	defer func() {
+		if registrar != nil {
+			registrar.Close()
+		}
+	}()
 
-	// 创建文件
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+	// 设置读取超时
+	wsConfig := config.GlobalConfig.Server.WebSocket
+	if wsConfig.ReadTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(time.Duration(wsConfig.ReadTimeout) * time.Second))
 	}
-	defer file.Close()
 
-	// 将 float32 转换为 int16
-	// float32 范围是 [-1.0, 1.0]，需要转换为 int16 范围 [-32768, 32767]
-	int16Data := make([]int, len(audioData))
-	normalizeFactor := config.GlobalConfig.Audio.NormalizeFactor
-	for i, sample := range audioData {
-		// 限制范围到 [-1.0, 1.0]
-		if sample > 1.0 {
-			sample = 1.0
-		} else if sample < -1.0 {
-			sample = -1.0
+	// 设置 WebSocket 协议层 ping handler，收到 ping 时刷新超时并自动回复 pong
+	conn.SetPingHandler(func(appData string) error {
+		if wsConfig.ReadTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(time.Duration(wsConfig.ReadTimeout) * time.Second))
 		}
-		// 转换为 int16
-		int16Data[i] = int(sample * normalizeFactor)
+		return conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(time.Second))
+	})
+
+	// 发送连接确认消息
+	if err := conn.WriteJSON(map[string]interface{}{
+		"type":        "connection",
+		"message":     "WebSocket connected, ready for audio (multi-round enabled)",
+		"sample_rate": sampleRate,
+	}); err != nil {
+		logger.Errorf("Failed to send connection message: %v", err)
+		return
 	}
 
-	// 创建音频格式
-	format := &audio.Format{
-		NumChannels: 1, // 单声道
-		SampleRate:  sampleRate,
+	// 重置状态，准备下一轮
+	totalAudioSamples := 0
+	roundCount := 0 // 注册轮次计数
+	resetRound := func() {
+		registrar.Close()
+		registrar = createRegistrar()
+		totalAudioSamples = 0
 	}
 
-	// 创建 WAV 编码器
-	encoder := wav.NewEncoder(file, format.SampleRate, 16, format.NumChannels, 1)
+	for {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				logger.Warnf("WebSocket read error: %v", err)
+			} else {
+				logger.Debugf("WebSocket: Connection closed normally or read error: %v", err)
+			}
+			break
+		}
 
-	// 创建音频缓冲区
-	buf := &audio.IntBuffer{
-		Format:         format,
-		SourceBitDepth: 16,
-		Data:           int16Data,
-	}
+		// 刷新读超时
+		if wsConfig.ReadTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(time.Duration(wsConfig.ReadTimeout) * time.Second))
+		}
 
-	// 写入音频数据
-	if err := encoder.Write(buf); err != nil {
-		return fmt.Errorf("failed to write audio data: %w", err)
-	}
+		// 检查消息大小
+		if wsConfig.MaxMessageSize > 0 && len(message) > wsConfig.MaxMessageSize {
+			logger.Warnf("WebSocket: Message too large: %d bytes (max: %d)", len(message), wsConfig.MaxMessageSize)
+			conn.WriteJSON(map[string]interface{}{
+				"type":    "error",
+				"code":    CodeInvalidArgument,
+				"message": "message too large",
+			})
+			continue
+		}
 
-	// 关闭编码器
-	if err := encoder.Close(); err != nil {
-		return fmt.Errorf("failed to close encoder: %w", err)
-	}
+		// 处理文本消息（控制消息）
+		if messageType == websocket.TextMessage {
+			var controlMsg map[string]interface{}
+			if err := json.Unmarshal(message, &controlMsg); err != nil {
+				logger.Warnf("WebSocket: Failed to unmarshal text message: %v", err)
+				continue
+			}
 
-	logger.Debugf("Saved audio file: %s, samples: %d, duration: %.2fs", filePath, len(audioData), float64(len(audioData))/float64(sampleRate))
-	return nil
-}
+			action, _ := controlMsg["action"].(string)
+			switch action {
+			case "finish":
+				// 完成当前轮次并保存样本
+				roundCount++
+				roundID, _ := newRandomID()
+				logger.Debugf("WebSocket: Finish action received (registration round %d), total audio samples: %d", roundCount, totalAudioSamples)
+				sample, filteredAudio, err := registrar.FinishAndRegister()
+				if err != nil {
+					logger.Errorf("WebSocket: FinishAndRegister failed: %v", err)
+					auditRound(&AuditEntry{
+						Decision: AuditDecisionError,
+						RoundID:  roundID,
+						Round:    roundCount,
+						Error:    err.Error(),
+					})
+					errorMsg := map[string]interface{}{
+						"type":    "error",
+						"code":    errorCodeOf(err),
+						"message": err.Error(),
+						"round":   roundCount,
+					}
+					var duplicateErr *DuplicateSpeakerError
+					if errors.As(err, &duplicateErr) {
+						errorMsg["conflicting_speaker_id"] = duplicateErr.Match.SpeakerID
+						errorMsg["duplicate"] = duplicateErr.Match
+					}
+					conn.WriteJSON(errorMsg)
+					// 重置状态，准备下一轮（即使出错也允许继续）
+					resetRound()
+					continue
+				}
 
-// saveRegisterAudioToWAV 将注册音频数据保存为 WAV 文件（文件名加前缀 "register_"）
-func saveRegisterAudioToWAV(audioData []float32, sampleRate int, uid, agentID string) error {
-	if len(audioData) == 0 {
-		return fmt.Errorf("audio data is empty")
-	}
+				roundEntry := &AuditEntry{
+					RoundID: roundID,
+					Round:   roundCount,
+					Detail:  map[string]interface{}{"uuid": uuid, "point_id": sample.PointID, "sample_index": sample.SampleIndex},
+				}
+				if sample.Duplicate != nil {
+					roundEntry.Detail["conflicting_speaker_id"] = sample.Duplicate.SpeakerID
+				}
+				auditRound(roundEntry)
+
+				// 归档注册音频（异步保存，不阻塞响应）
+				record := &AudioRecord{
+					Kind:        AudioKindRegister,
+					UID:         uid,
+					AgentID:     agentID,
+					SpeakerID:   speakerID,
+					SpeakerUUID: uuid,
+					SampleIndex: &sample.SampleIndex,
+					PointID:     sample.PointID,
+					RoundID:     roundID,
+					Round:       roundCount,
+				}
+				go func() {
+					if err := h.manager.ArchiveAudio(record, filteredAudio, sampleRate); err != nil {
+						logger.Warnf("WebSocket: Failed to save register audio file (round %d): %v", record.Round, err)
+					}
+				}()
+
+				result := map[string]interface{}{
+					"speaker_id":   speakerID,
+					"speaker_name": speakerName,
+					"uuid":         uuid,
+					"point_id":     sample.PointID,
+					"sample_index": sample.SampleIndex,
+					"duration":     float64(len(filteredAudio)) / float64(sampleRate),
+				}
+				// 重复声纹检测为 warn 模式时返回冲突的说话人
+				if sample.Duplicate != nil {
+					result["conflicting_speaker_id"] = sample.Duplicate.SpeakerID
+					result["duplicate"] = sample.Duplicate
+				}
+				conn.WriteJSON(map[string]interface{}{
+					"type":   "result",
+					"result": result,
+					"round":  roundCount,
+				})
+				logger.Infof("WebSocket: Registered sample %d for speaker %s (round %d)", sample.SampleIndex, speakerID, roundCount)
 
-	// 确定保存目录
-	saveDir := config.GlobalConfig.Speaker.AudioSaveDir
-	if saveDir == "" {
-		// 如果未指定，使用 data_dir
-		saveDir = config.GlobalConfig.Speaker.DataDir
-	}
-	if saveDir == "" {
-		saveDir = "data/speaker"
-	}
+				// 重置状态，准备下一轮注册
+				resetRound()
+				conn.WriteJSON(map[string]interface{}{
+					"type":    "ready",
+					"message": "Ready for next round",
+					"round":   roundCount + 1,
+				})
 
-	// 创建保存目录（如果不存在）
-	if err := os.MkdirAll(saveDir, 0755); err != nil {
-		return fmt.Errorf("failed to create save directory: %w", err)
-	}
+			case "cancel":
+				// 丢弃当前轮次的音频，重置状态
+				logger.Infof("WebSocket: Cancel action received (registration round %d), resetting state", roundCount+1)
+				resetRound()
+				conn.WriteJSON(map[string]interface{}{
+					"type":    "cancelled",
+					"message": "Current round cancelled, ready for next round",
+					"round":   roundCount + 1,
+				})
 
-	// 生成文件名：register_时间戳_uid_agentid.wav
-	timestamp := time.Now().Format("20060102_150405")
-	var filename string
-	if uid != "" && agentID != "" {
-		filename = fmt.Sprintf("register_%s_%s_%s.wav", timestamp, uid, agentID)
-	} else if uid != "" {
-		filename = fmt.Sprintf("register_%s_%s.wav", timestamp, uid)
-	} else if agentID != "" {
-		filename = fmt.Sprintf("register_%s_%s.wav", timestamp, agentID)
-	} else {
-		filename = fmt.Sprintf("register_%s.wav", timestamp)
-	}
+			case "close":
+				// 显式关闭连接
+				logger.Infof("WebSocket: Close action received, closing connection after %d registration rounds", roundCount)
+				conn.WriteJSON(map[string]interface{}{
+					"type":         "closing",
+					"message":      "Connection closing",
+					"total_rounds": roundCount,
+				})
+				return
+
+			default:
+				logger.Warnf("WebSocket: Unknown action: %q", action)
+			}
+			continue
+		}
 
-	// 清理文件名中的非法字符
-	filename = strings.ReplaceAll(filename, "/", "_")
-	filename = strings.ReplaceAll(filename, "\\", "_")
-	filename = strings.ReplaceAll(filename, ":", "_")
+		// 处理二进制消息（float32 小端序音频数据）
+		if messageType == websocket.BinaryMessage {
+			if len(message)%4 != 0 {
+				logger.Warnf("WebSocket: Invalid audio data length: %d bytes (not divisible by 4)", len(message))
+				conn.WriteJSON(map[string]interface{}{
+					"type":    "error",
+					"code":    CodeInvalidArgument,
+					"message": "invalid audio data length",
+				})
+				continue
+			}
 
-	filePath := filepath.Join(saveDir, filename)
+			sampleCount := len(message) / 4
+			audioData := make([]float32, sampleCount)
+			for i := 0; i < len(audioData); i++ {
+				bits := binary.LittleEndian.Uint32(message[i*4 : (i+1)*4])
+				audioData[i] = math.Float32frombits(bits)
+			}
 
-	// 创建文件
-	file, err := os.Create(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer file.Close()
+			if err := registrar.AcceptAudio(audioData); err != nil {
+				logger.Errorf("WebSocket: Failed to accept audio chunk: %v", err)
+				conn.WriteJSON(map[string]interface{}{
+					"type":    "error",
+					"code":    errorCodeOf(err),
+					"message": err.Error(),
+				})
+				return
+			}
+			totalAudioSamples += sampleCount
 
-	// 将 float32 转换为 int16
-	// float32 范围是 [-1.0, 1.0]，需要转换为 int16 范围 [-32768, 32767]
-	int16Data := make([]int, len(audioData))
-	normalizeFactor := config.GlobalConfig.Audio.NormalizeFactor
-	for i, sample := range audioData {
-		// 限制范围到 [-1.0, 1.0]
-		if sample > 1.0 {
-			sample = 1.0
-		} else if sample < -1.0 {
-			sample = -1.0
+			conn.WriteJSON(map[string]interface{}{
+				"type":        "audio_received",
+				"samples":     sampleCount,
+				"duration_ms": float64(sampleCount) / float64(sampleRate) * 1000,
+			})
 		}
-		// 转换为 int16
-		int16Data[i] = int(sample * normalizeFactor)
 	}
 
-	// 创建音频格式
-	format := &audio.Format{
-		NumChannels: 1, // 单声道
-		SampleRate:  sampleRate,
-	}
+	logger.Infof("WebSocket: Registration connection closed, total rounds: %d", roundCount)
+}
 
-	// 创建 WAV 编码器
-	encoder := wav.NewEncoder(file, format.SampleRate, 16, format.NumChannels, 1)
+// parseInt 解析整数（辅助函数）
+func parseInt(s string) (int, error) {
+	var result int
+	_, err := fmt.Sscanf(s, "%d", &result)
+	return result, err
+}
 
-	// 创建音频缓冲区
-	buf := &audio.IntBuffer{
-		Format:         format,
-		SourceBitDepth: 16,
-		Data:           int16Data,
-	}
+// parseFloat32 解析浮点数（辅助函数）
+func parseFloat32(s string) (float32, error) {
+	var result float32
+	_, err := fmt.Sscanf(s, "%f", &result)
+	return result, err
+}
 
-	// 写入音频数据
-	if err := encoder.Write(buf); err != nil {
-		return fmt.Errorf("failed to write audio data: %w", err)
+// RegisterAudioDir 返回音频归档的根目录
+func RegisterAudioDir() string {
+	saveDir := config.GlobalConfig.Speaker.AudioSaveDir
+	if saveDir == "" {
+		// 如果未指定，使用 data_dir
+		saveDir = config.GlobalConfig.Speaker.DataDir
 	}
-
-	// 关闭编码器
-	if err := encoder.Close(); err != nil {
-		return fmt.Errorf("failed to close encoder: %w", err)
+	if saveDir == "" {
+		saveDir = "data/speaker"
 	}
-
-	logger.Debugf("Saved register audio file: %s, samples: %d, duration: %.2fs", filePath, len(audioData), float64(len(audioData))/float64(sampleRate))
-	return nil
+	return saveDir
 }
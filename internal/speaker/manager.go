@@ -2,7 +2,10 @@ package speaker
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,69 +16,223 @@ import (
 	sherpa "github.com/k2-fsa/sherpa-onnx-go/sherpa_onnx"
 )
 
+// maxTopK 单次识别最多返回的候选说话人数量
+const maxTopK = 50
+
 // Manager 声纹识别管理器
 type Manager struct {
-	extractor    *sherpa.SpeakerEmbeddingExtractor
-	threshold    float32
-	embeddingDim int
+	extractorPool pool.ExtractorPoolInterface // 声纹特征提取器池（提取器不是线程安全的，每次提取独占一个实例）
+	embeddingDim  int
+	modelName    string // 声纹模型标识（写入样本点，导出时记录，导入时校验）
 	dataDir      string
 
+	// 判定阈值（支持配置热加载更新）
+	threshold      float32 // 余弦相似度阈值
+	normThreshold  float32 // 归一化分数阈值（仅在启用分数归一化时使用）
+	thresholdMutex sync.RWMutex
+
 	// Qdrant 向量数据库客户端（唯一存储）
 	vectorDB      *QdrantVectorDB
 	vectorDBMutex sync.RWMutex
 
+	// 按说话人加锁，保证 sample_index 分配与写入的原子性（key: uid:speaker_id）
+	// 引用计数归零时删除，避免只出现一次的说话人（如校准、匿名说话人）的锁常驻内存
+	speakerLocks      map[string]*speakerLock
+	speakerLocksMutex sync.Mutex
+
 	// VAD池（用于过滤静音）
 	vadPool pool.VADPoolInterface
+
+	// 分数归一化器（可选，未启用时为 nil）
+	scoreNorm *ScoreNormalizer
+
+	// 阈值校准配置（HTTP 接口只能读取该目录下的标注数据）
+	calibrationConfig CalibrationConfig
+
+	// 管理接口鉴权配置
+	adminConfig AdminConfig
+
+	// 重复声纹检测配置（支持配置热加载更新，受 thresholdMutex 保护）
+	duplicateCheck DuplicateCheckConfig
+
+	// 语音识别器（可选，用于挑战-应答验证）和未使用的挑战
+	recognizer      *sherpa.OfflineRecognizer
+	challengeConfig ChallengeConfig
+	challenges      challengeStore
+
+	// 注册/识别音频归档
+	audioArchive *AudioArchive
+
+	// 操作审计日志（未启用时为 nil）
+	auditLog *AuditLog
+
+	// 流式识别的 VAD 门控配置
+	streamingConfig StreamingConfig
+
+	// 未识别说话人的自动注册配置
+	autoEnrollConfig AutoEnrollConfig
 }
 
 // Config 声纹识别配置
 type Config struct {
 	ModelPath  string  `json:"model_path"`
+	ModelID    string  `json:"model_id"` // 模型标识，为空时使用模型文件名
 	NumThreads int     `json:"num_threads"`
 	Provider   string  `json:"provider"`
 	Threshold  float32 `json:"threshold"`
 	DataDir    string  `json:"data_dir"` // 保留用于其他用途（如临时文件）
 
+	// 声纹特征提取器池配置
+	ExtractorPool ExtractorPoolConfig `json:"extractor_pool"`
+
 	// 向量数据库配置（必需）
 	VectorDB struct {
 		Host           string `json:"host"`            // Qdrant 地址，默认 localhost
 		Port           int    `json:"port"`            // Qdrant 端口，默认 6334
 		CollectionName string `json:"collection_name"` // Collection 名称，默认 speaker_embeddings
+		LegacyModel    string `json:"legacy_model"`    // 没有 model 字段的旧数据来自哪个模型，为空时视为当前模型
+
+		// 鉴权和 TLS（托管 Qdrant）
+		APIKey             string `json:"api_key"`
+		UseTLS             bool   `json:"use_tls"`
+		CACertFile         string `json:"ca_cert_file"`
+		InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+
+		// 调用超时、重试和熔断（见 QdrantResilienceConfig）
+		TimeoutMs         int `json:"timeout_ms"`
+		MaxRetries        int `json:"max_retries"`
+		RetryBackoffMs    int `json:"retry_backoff_ms"`
+		BreakerThreshold  int `json:"breaker_threshold"`
+		BreakerCooldownMs int `json:"breaker_cooldown_ms"`
 	} `json:"vector_db"`
+
+	// 分数归一化配置（可选）
+	ScoreNorm ScoreNormConfig `json:"score_norm"`
+
+	// 阈值校准配置
+	Calibration CalibrationConfig `json:"calibration"`
+
+	// 管理接口（/admin/*）鉴权配置
+	Admin AdminConfig `json:"admin"`
+
+	// 注册时的重复声纹检测（可选）
+	DuplicateCheck DuplicateCheckConfig `json:"duplicate_check"`
+
+	// 文本相关验证（挑战-应答）配置
+	Challenge ChallengeConfig `json:"challenge"`
+
+	// 音频归档保留策略
+	AudioArchive AudioArchiveConfig `json:"audio_archive"`
+
+	// 操作审计日志
+	Audit AuditConfig `json:"audit"`
+
+	// 流式识别配置
+	Streaming StreamingConfig `json:"streaming"`
+
+	// 未识别说话人自动注册为匿名说话人（可选）
+	AutoEnroll AutoEnrollConfig `json:"auto_enroll"`
+}
+
+// ExtractorPoolConfig 声纹特征提取器池配置
+type ExtractorPoolConfig struct {
+	PoolSize          int `json:"pool_size"`           // 提取器数量，默认 1
+	NumThreads        int `json:"num_threads"`         // 单个提取器的线程数，为 0 时使用 Config.NumThreads
+	CheckoutTimeoutMs int `json:"checkout_timeout_ms"` // 获取提取器的最长等待时间，默认 5000
+}
+
+const (
+	// DuplicateActionReject 检测到重复声纹时拒绝注册
+	DuplicateActionReject = "reject"
+	// DuplicateActionWarn 检测到重复声纹时仍然注册，并在结果中返回冲突的说话人
+	DuplicateActionWarn = "warn"
+)
+
+// DuplicateCheckConfig 重复声纹检测配置
+type DuplicateCheckConfig struct {
+	Enabled   bool    `json:"enabled"`
+	Threshold float32 `json:"threshold"` // 与其他说话人的余弦相似度达到该值视为重复
+	Action    string  `json:"action"`    // reject（默认）或 warn
 }
 
+// DuplicateMatch 与新样本声纹重复的已有说话人
+type DuplicateMatch struct {
+	SpeakerID   string  `json:"speaker_id"`
+	SpeakerName string  `json:"speaker_name"`
+	Similarity  float32 `json:"similarity"`
+}
+
+// DuplicateSpeakerError 检测到重复声纹并拒绝注册时返回的错误
+type DuplicateSpeakerError struct {
+	Match DuplicateMatch
+}
+
+func (e *DuplicateSpeakerError) Error() string {
+	return fmt.Sprintf("voice matches existing speaker %s (similarity %.4f)", e.Match.SpeakerID, e.Match.Similarity)
+}
+
+func (e *DuplicateSpeakerError) Unwrap() error { return ErrDuplicateSpeaker }
+
 // NewManager 创建声纹识别管理器
 func NewManager(config *Config, vadPool pool.VADPoolInterface) (*Manager, error) {
 	// 确保数据目录存在（用于其他用途，如临时文件）
 	if config.DataDir != "" {
 		if err := os.MkdirAll(config.DataDir, 0755); err != nil {
-			return nil, fmt.Errorf("failed to create data directory: %v", err)
+			return nil, fmt.Errorf("failed to create data directory: %w", err)
 		}
 	}
 
 	// 创建声纹特征提取器配置
+	numThreads := config.ExtractorPool.NumThreads
+	if numThreads <= 0 {
+		numThreads = config.NumThreads
+	}
 	extractorConfig := &sherpa.SpeakerEmbeddingExtractorConfig{
 		Model:      config.ModelPath,
-		NumThreads: config.NumThreads,
+		NumThreads: numThreads,
 		Debug:      0,
 		Provider:   config.Provider,
 	}
 
-	// 创建声纹特征提取器
-	extractor := sherpa.NewSpeakerEmbeddingExtractor(extractorConfig)
-	if extractor == nil {
-		return nil, fmt.Errorf("failed to create speaker embedding extractor")
+	// 创建声纹特征提取器池
+	extractorPool := pool.NewExtractorPool(&pool.ExtractorPoolConfig{
+		ModelConfig:     extractorConfig,
+		PoolSize:        config.ExtractorPool.PoolSize,
+		CheckoutTimeout: time.Duration(config.ExtractorPool.CheckoutTimeoutMs) * time.Millisecond,
+	})
+	if err := extractorPool.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to initialize speaker extractor pool: %w", err)
 	}
 
 	// 获取特征维度
-	dim := extractor.Dim()
+	dim := extractorPool.Dim()
 	logger.Infof("Speaker embedding dimension: %d", dim)
 
+	// 模型标识：写入每个样本点，用于拒绝混用不同模型的向量
+	modelID := config.ModelID
+	if modelID == "" {
+		modelID = filepath.Base(config.ModelPath)
+	}
+
 	// 初始化 Qdrant 向量数据库
 	qdrantConfig := &QdrantConfig{
 		Host:           config.VectorDB.Host,
 		Port:           config.VectorDB.Port,
 		CollectionName: config.VectorDB.CollectionName,
+		ModelID:        modelID,
+		LegacyModel:    config.VectorDB.LegacyModel,
+
+		APIKey:             config.VectorDB.APIKey,
+		UseTLS:             config.VectorDB.UseTLS,
+		CACertFile:         config.VectorDB.CACertFile,
+		InsecureSkipVerify: config.VectorDB.InsecureSkipVerify,
+		Resilience: QdrantResilienceConfig{
+			TimeoutMs:         config.VectorDB.TimeoutMs,
+			MaxRetries:        config.VectorDB.MaxRetries,
+			RetryBackoffMs:    config.VectorDB.RetryBackoffMs,
+			BreakerThreshold:  config.VectorDB.BreakerThreshold,
+			BreakerCooldownMs: config.VectorDB.BreakerCooldownMs,
+		},
 	}
 
 	// 设置默认值
@@ -91,18 +248,88 @@ func NewManager(config *Config, vadPool pool.VADPoolInterface) (*Manager, error)
 
 	vectorDB, err := NewQdrantVectorDB(qdrantConfig, dim)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize vector database: %v", err)
+		extractorPool.Shutdown()
+		return nil, fmt.Errorf("failed to initialize vector database: %w", err)
+	}
+
+	// 初始化分数归一化器（可选）
+	var scoreNorm *ScoreNormalizer
+	if config.ScoreNorm.Enabled {
+		scoreNorm, err = NewScoreNormalizer(&config.ScoreNorm, dim, vectorDB)
+		if err != nil {
+			extractorPool.Shutdown()
+			vectorDB.Close()
+			return nil, fmt.Errorf("failed to initialize score normalization: %w", err)
+		}
+		// Qdrant 已就绪时提前加载 Collection 中的冒名者集合，失败时在首次使用时重试
+		if vectorDB.Status().State == QdrantStateReady {
+			if err := scoreNorm.ensureCohort(); err != nil {
+				logger.Warnf("Impostor cohort is not available yet: %v", err)
+			}
+		}
+	}
+
+	// 初始化审计日志（可选）
+	auditLog, err := NewAuditLog(config.Audit, config.DataDir)
+	if err != nil {
+		extractorPool.Shutdown()
+		vectorDB.Close()
+		return nil, fmt.Errorf("failed to initialize audit log: %w", err)
 	}
 
 	manager := &Manager{
-		extractor:    extractor,
-		threshold:    config.Threshold,
-		embeddingDim: dim,
-		dataDir:      config.DataDir,
-		vectorDB:     vectorDB,
-		vadPool:      vadPool,
+		auditLog:      auditLog,
+		extractorPool: extractorPool,
+		embeddingDim:  dim,
+		modelName:     modelID,
+		dataDir:       config.DataDir,
+		threshold:     config.Threshold,
+		normThreshold: config.ScoreNorm.Threshold,
+		vectorDB:      vectorDB,
+		vadPool:       vadPool,
+		scoreNorm:     scoreNorm,
+		speakerLocks:  make(map[string]*speakerLock),
+	}
+	manager.SetDuplicateCheck(config.DuplicateCheck)
+
+	// 挑战-应答配置默认值
+	manager.challengeConfig = config.Challenge
+	if manager.challengeConfig.TTLSeconds <= 0 {
+		manager.challengeConfig.TTLSeconds = 60
+	}
+	if manager.challengeConfig.Length <= 0 {
+		manager.challengeConfig.Length = 6
+	}
+	if manager.challengeConfig.MaxCharErrorRate <= 0 {
+		manager.challengeConfig.MaxCharErrorRate = 0.2
+	}
+	manager.challenges.challenges = make(map[string]*Challenge)
+
+	manager.calibrationConfig = config.Calibration
+	manager.adminConfig = config.Admin
+
+	// 流式识别配置默认值
+	manager.streamingConfig = config.Streaming
+	if manager.streamingConfig.MinSpeechMs == 0 {
+		manager.streamingConfig.MinSpeechMs = 1000
+	}
+	if manager.streamingConfig.EdgePaddingMs <= 0 {
+		manager.streamingConfig.EdgePaddingMs = 100
+	}
+	if manager.streamingConfig.MaxRegisterSeconds <= 0 {
+		manager.streamingConfig.MaxRegisterSeconds = 60
 	}
 
+	// 自动注册配置默认值
+	manager.autoEnrollConfig = config.AutoEnroll
+	if manager.autoEnrollConfig.MaxSamples <= 0 {
+		manager.autoEnrollConfig.MaxSamples = 5
+	}
+
+	// 音频归档及其后台清理
+	manager.audioArchive = NewAudioArchive(RegisterAudioDir(), config.AudioArchive, manager.vectorDB.ExistingPointIDs)
+	manager.audioArchive.Start()
+
 	logger.Infof("✅ Speaker Manager initialized with Qdrant vector database and VAD pool")
 	return manager, nil
 }
@@ -114,9 +341,19 @@ func (m *Manager) Close() {
 		m.vectorDB.Close()
 	}
 
-	// 释放提取器
-	if m.extractor != nil {
-		sherpa.DeleteSpeakerEmbeddingExtractor(m.extractor)
+	// 停止音频归档清理
+	if m.audioArchive != nil {
+		m.audioArchive.Stop()
+	}
+
+	// 发送完剩余的审计记录
+	if m.auditLog != nil {
+		m.auditLog.Close()
+	}
+
+	// 释放提取器池
+	if m.extractorPool != nil {
+		m.extractorPool.Shutdown()
 	}
 
 	logger.Infof("Speaker Manager closed, all resources released")
@@ -124,8 +361,16 @@ func (m *Manager) Close() {
 
 // extractEmbedding 从音频数据提取声纹特征（私有方法）
 func (m *Manager) extractEmbedding(audioData []float32, sampleRate int) ([]float32, error) {
+	// 从池中获取提取器
+	instance, err := m.extractorPool.Get()
+	if err != nil {
+		return nil, err
+	}
+	defer m.extractorPool.Put(instance)
+	extractor := instance.Extractor
+
 	// 创建音频流
-	stream := m.extractor.CreateStream()
+	stream := extractor.CreateStream()
 	defer sherpa.DeleteOnlineStream(stream)
 
 	// 接受音频数据
@@ -133,14 +378,19 @@ func (m *Manager) extractEmbedding(audioData []float32, sampleRate int) ([]float
 	stream.InputFinished()
 
 	// 检查是否准备就绪
-	if !m.extractor.IsReady(stream) {
-		return nil, fmt.Errorf("insufficient audio data for embedding extraction")
+	if !extractor.IsReady(stream) {
+		return nil, newError(ErrInsufficientAudio, "insufficient audio data for embedding extraction")
 	}
 
 	// 提取特征
-	embedding := m.extractor.Compute(stream)
+	embedding := extractor.Compute(stream)
 	if len(embedding) == 0 {
-		return nil, fmt.Errorf("failed to extract embedding")
+		return nil, newError(ErrQualityRejected, "failed to extract embedding")
+	}
+	for _, v := range embedding {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return nil, newError(ErrQualityRejected, "embedding contains invalid values, audio may be corrupted")
+		}
 	}
 
 	// 注意：不需要手动归一化向量
@@ -159,7 +409,7 @@ func (m *Manager) filterSilenceWithVAD(audioData []float32, sampleRate int) ([]f
 	// 获取VAD实例
 	vadInstance, err := m.vadPool.Get()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get VAD instance: %v", err)
+		return nil, fmt.Errorf("failed to get VAD instance: %w", err)
 	}
 	defer m.vadPool.Put(vadInstance)
 
@@ -195,7 +445,7 @@ func (m *Manager) filterSilenceWithVAD(audioData []float32, sampleRate int) ([]f
 		// 调用VAD处理
 		_, flag, err := pool.GetInstance().ProcessAudio(tenVADInstance.Handle, int16Frame)
 		if err != nil {
-			return nil, fmt.Errorf("TEN-VAD ProcessAudio error: %v", err)
+			return nil, fmt.Errorf("TEN-VAD ProcessAudio error: %w", err)
 		}
 
 		// flag == 1 表示语音，保留该帧；flag == 0 表示静音，丢弃
@@ -220,7 +470,7 @@ func (m *Manager) filterSilenceWithVADKeepEdges(audioData []float32, sampleRate
 	// 获取VAD实例
 	vadInstance, err := m.vadPool.Get()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get VAD instance: %v", err)
+		return nil, fmt.Errorf("failed to get VAD instance: %w", err)
 	}
 	defer m.vadPool.Put(vadInstance)
 
@@ -267,7 +517,7 @@ func (m *Manager) filterSilenceWithVADKeepEdges(audioData []float32, sampleRate
 		// 调用VAD处理
 		_, flag, err := pool.GetInstance().ProcessAudio(tenVADInstance.Handle, int16Frame)
 		if err != nil {
-			return nil, fmt.Errorf("TEN-VAD ProcessAudio error: %v", err)
+			return nil, fmt.Errorf("TEN-VAD ProcessAudio error: %w", err)
 		}
 
 		// flag == 1 表示语音，flag == 0 表示静音
@@ -330,65 +580,388 @@ func (m *Manager) ExtractEmbedding(audioData []float32, sampleRate int) ([]float
 	return m.extractEmbedding(audioData, sampleRate)
 }
 
+// defaultThreshold 获取默认判定阈值：启用分数归一化时使用归一化分数的阈值，否则使用余弦相似度阈值
+func (m *Manager) defaultThreshold() float32 {
+	m.thresholdMutex.RLock()
+	defer m.thresholdMutex.RUnlock()
+
+	if m.scoreNorm != nil {
+		return m.normThreshold
+	}
+	return m.threshold
+}
+
+// GetThreshold 获取余弦相似度阈值
+func (m *Manager) GetThreshold() float32 {
+	m.thresholdMutex.RLock()
+	defer m.thresholdMutex.RUnlock()
+	return m.threshold
+}
+
+// SetThreshold 更新余弦相似度阈值（用于配置热加载）
+func (m *Manager) SetThreshold(threshold float32) {
+	if threshold <= 0 {
+		return
+	}
+	m.thresholdMutex.Lock()
+	defer m.thresholdMutex.Unlock()
+	if m.threshold != threshold {
+		logger.Infof("Speaker threshold updated: %.4f -> %.4f", m.threshold, threshold)
+		m.threshold = threshold
+	}
+}
+
+// SetScoreNormThreshold 更新归一化分数阈值（用于配置热加载）
+func (m *Manager) SetScoreNormThreshold(threshold float32) {
+	m.thresholdMutex.Lock()
+	defer m.thresholdMutex.Unlock()
+	if m.normThreshold != threshold {
+		logger.Infof("Speaker score normalization threshold updated: %.4f -> %.4f", m.normThreshold, threshold)
+		m.normThreshold = threshold
+	}
+}
+
+// SetDuplicateCheck 更新重复声纹检测配置
+func (m *Manager) SetDuplicateCheck(cfg DuplicateCheckConfig) {
+	if cfg.Action != DuplicateActionWarn {
+		cfg.Action = DuplicateActionReject
+	}
+	m.thresholdMutex.Lock()
+	m.duplicateCheck = cfg
+	m.thresholdMutex.Unlock()
+}
+
+// checkDuplicate 在同一 uid/agent_id 下查找与新样本声纹相似度超过阈值的其他说话人
+// 未启用或没有重复时返回 nil；action 为 reject 时返回 DuplicateSpeakerError
+func (m *Manager) checkDuplicate(uid, agentID, speakerID string, embedding []float32) (*DuplicateMatch, error) {
+	m.thresholdMutex.RLock()
+	cfg := m.duplicateCheck
+	m.thresholdMutex.RUnlock()
+	if !cfg.Enabled || cfg.Threshold <= 0 {
+		return nil, nil
+	}
+
+	// 多取几个，排除说话人自身和匿名说话人后仍有最相似的其他说话人
+	// 匿名说话人是自动注册产生的，正式注册同一个人不算重复（之后可以合并）
+	results, err := m.vectorDB.SearchGroupedBySpeaker(uid, agentID, "", "", embedding, 5, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search existing speakers: %w", err)
+	}
+
+	for _, r := range results {
+		if (r.SpeakerID == speakerID && r.AgentID == agentID) || IsAnonymousSpeaker(r.SpeakerID) {
+			continue
+		}
+		if r.Confidence < cfg.Threshold {
+			break
+		}
+		match := &DuplicateMatch{
+			SpeakerID:   r.SpeakerID,
+			SpeakerName: r.SpeakerName,
+			Similarity:  r.Confidence,
+		}
+		logger.Warnf("Enrollment for speaker %s (uid %s, agent_id %s) matches existing speaker %s, similarity: %.4f, action: %s",
+			speakerID, uid, agentID, r.SpeakerID, r.Confidence, cfg.Action)
+		if cfg.Action == DuplicateActionReject {
+			return match, &DuplicateSpeakerError{Match: *match}
+		}
+		return match, nil
+	}
+
+	return nil, nil
+}
+
+// ScoreNormEnabled 是否启用了分数归一化
+func (m *Manager) ScoreNormEnabled() bool {
+	return m.scoreNorm != nil
+}
+
+// testStats 计算被测向量的冒名者统计量，未启用分数归一化时返回 nil
+// 冒名者集合尚未加载时先加载，加载失败时返回错误（不能退回原始分数，阈值是按归一化分数设置的）
+func (m *Manager) testStats(embedding []float32) (*cohortStats, error) {
+	if m.scoreNorm == nil {
+		return nil, nil
+	}
+	if err := m.scoreNorm.ensureCohort(); err != nil {
+		return nil, err
+	}
+	stats := m.scoreNorm.stats(embedding)
+	return &stats, nil
+}
+
+// scoreResult 计算搜索结果的决策分数
+// 未启用分数归一化时为原始余弦相似度；启用时为归一化分数，并同时返回归一化分数
+func (m *Manager) scoreResult(r SearchResult, testStats *cohortStats) (float32, *float32) {
+	if m.scoreNorm == nil || testStats == nil || len(r.Embedding) == 0 {
+		return r.Confidence, nil
+	}
+	enrollStats := m.scoreNorm.stats(r.Embedding)
+	normalized := m.scoreNorm.normalize(r.Confidence, enrollStats, *testStats)
+	return normalized, &normalized
+}
+
+// scoreNormMethod 返回当前使用的分数归一化方法，未启用时为空字符串
+func (m *Manager) scoreNormMethod() string {
+	if m.scoreNorm == nil {
+		return ""
+	}
+	return m.scoreNorm.Method()
+}
+
+// GetModelName 获取声纹模型标识
+func (m *Manager) GetModelName() string {
+	return m.modelName
+}
+
 // GetEmbeddingDim 获取 embedding 维度
 func (m *Manager) GetEmbeddingDim() int {
 	return m.embeddingDim
 }
 
-// RegisterSpeaker 注册声纹（支持 UID 和 Agent ID 维度隔离）
-func (m *Manager) RegisterSpeaker(uid, agentID, speakerID, speakerName, uuid string, audioData []float32, sampleRate int) error {
+// RegisterSpeaker 注册声纹（支持 UID 和 Agent ID 维度隔离），返回新样本的元数据
+func (m *Manager) RegisterSpeaker(uid, agentID, speakerID, speakerName, uuid string, audioData []float32, sampleRate int) (*SampleInfo, error) {
 	if uid == "" {
-		return fmt.Errorf("uid is required")
+		return nil, newError(ErrInvalidArgument, "uid is required")
 	}
 
 	if agentID == "" {
-		return fmt.Errorf("agent_id is required")
+		return nil, newError(ErrInvalidArgument, "agent_id is required")
 	}
 
 	if uuid == "" {
-		return fmt.Errorf("uuid is required")
+		return nil, newError(ErrInvalidArgument, "uuid is required")
 	}
 
 	// 注意：音频数据应该在调用此方法之前已经过滤过静音（保留前后100ms）
 	// 提取声纹特征
 	embedding, err := m.extractEmbedding(audioData, sampleRate)
 	if err != nil {
-		return fmt.Errorf("failed to extract embedding: %v", err)
+		return nil, fmt.Errorf("failed to extract embedding: %w", err)
 	}
 
 	// 验证嵌入向量维度
 	if len(embedding) != m.embeddingDim {
-		return fmt.Errorf("embedding dimension mismatch: expected %d, got %d", m.embeddingDim, len(embedding))
+		return nil, newError(ErrInvalidArgument, "embedding dimension mismatch: expected %d, got %d", m.embeddingDim, len(embedding))
+	}
+
+	// 分配 sample_index 与写入需要在同一把锁内完成，避免并发注册得到相同的 sample_index
+	unlock := m.lockSpeaker(uid, speakerID)
+	defer unlock()
+
+	// 重复声纹检测：拒绝时直接返回 DuplicateSpeakerError
+	duplicate, err := m.checkDuplicate(uid, agentID, speakerID, embedding)
+	if err != nil {
+		return nil, err
 	}
 
-	// 查询该 speaker 已存在的样本数量（用于确定 sample_index）
-	sampleIndex, err := m.vectorDB.GetSpeakerSampleCount(uid, agentID, speakerID)
+	// 确定新样本的 sample_index（已有样本最大值 + 1，删除单个样本后也不会与已有样本冲突）
+	sampleIndex, err := m.nextSampleIndex(uid, agentID, speakerID)
 	if err != nil {
-		// 如果查询失败，可能是 speaker 不存在，从 0 开始
-		sampleIndex = 0
+		return nil, fmt.Errorf("failed to allocate sample index: %w", err)
 	}
 
 	// 插入到 Qdrant 向量数据库
 	now := time.Now().Unix()
-	err = m.vectorDB.Insert(uid, agentID, speakerID, speakerName, uuid, embedding, sampleIndex, now, now)
+	pointID, err := m.vectorDB.Insert(uid, agentID, speakerID, speakerName, uuid, embedding, sampleIndex, now, now)
 	if err != nil {
-		return fmt.Errorf("failed to insert to vector database: %v", err)
+		return nil, fmt.Errorf("failed to insert to vector database: %w", err)
 	}
 
 	logger.Infof("Successfully registered speaker %s (%s) for uid %s, agent_id %s, uuid %s, sample index: %d",
 		speakerID, speakerName, uid, agentID, uuid, sampleIndex)
+	return &SampleInfo{
+		PointID:     pointID,
+		SampleIndex: sampleIndex,
+		SpeakerID:   speakerID,
+		SpeakerName: speakerName,
+		UUID:        uuid,
+		AgentID:     agentID,
+		CreatedAt:   time.Unix(now, 0),
+		UpdatedAt:   time.Unix(now, 0),
+		Duplicate:   duplicate,
+	}, nil
+}
+
+// lockSpeaker 获取说话人级别的互斥锁，返回解锁函数
+// 锁粒度不区分 agent_id，以便 agent_id 可选的接口与注册接口互斥
+// 注意：仅保证单进程内的原子性；多实例部署时 Point ID 为随机 UUID，不会互相覆盖
+func (m *Manager) lockSpeaker(uid, speakerID string) func() {
+	key := uid + ":" + speakerID
+
+	m.speakerLocksMutex.Lock()
+	lock, ok := m.speakerLocks[key]
+	if !ok {
+		lock = &speakerLock{}
+		m.speakerLocks[key] = lock
+	}
+	lock.refs++
+	m.speakerLocksMutex.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+
+		m.speakerLocksMutex.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(m.speakerLocks, key)
+		}
+		m.speakerLocksMutex.Unlock()
+	}
+}
+
+// speakerLock 说话人锁，refs 为持有和等待该锁的数量（受 speakerLocksMutex 保护）
+type speakerLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// nextSampleIndex 计算说话人下一个样本的 sample_index（调用方需持有 lockSpeaker）
+func (m *Manager) nextSampleIndex(uid, agentID, speakerID string) (int, error) {
+	samples, err := m.vectorDB.ListSpeakerSamples(uid, agentID, speakerID)
+	if err != nil {
+		return 0, err
+	}
+	return nextSampleIndexOf(samples), nil
+}
+
+// nextSampleIndexOf 已有样本中最大的 sample_index 加一（样本序号可能不连续）
+func nextSampleIndexOf(samples []*SampleInfo) int {
+	next := 0
+	for _, sample := range samples {
+		if sample.SampleIndex >= next {
+			next = sample.SampleIndex + 1
+		}
+	}
+	return next
+}
+
+// RenameSpeaker 修改说话人名称（重写该说话人所有样本的 speaker_name）
+func (m *Manager) RenameSpeaker(uid, agentID, speakerID, speakerName string) error {
+	if uid == "" {
+		return newError(ErrInvalidArgument, "uid is required")
+	}
+
+	if speakerName == "" {
+		return newError(ErrInvalidArgument, "speaker_name is required")
+	}
+
+	// 与追加样本互斥，避免并发写入的新样本仍带旧名称
+	unlock := m.lockSpeaker(uid, speakerID)
+	defer unlock()
+
+	samples, err := m.vectorDB.ListSpeakerSamples(uid, agentID, speakerID)
+	if err != nil {
+		return fmt.Errorf("failed to list samples: %w", err)
+	}
+	if len(samples) == 0 {
+		return newError(ErrNotFound, "speaker %s not found", speakerID)
+	}
+
+	if err := m.vectorDB.UpdateSpeakerName(uid, agentID, speakerID, speakerName, time.Now().Unix()); err != nil {
+		return fmt.Errorf("failed to update vector database: %w", err)
+	}
+
+	logger.Infof("Successfully renamed speaker %s to %s for uid %s, agent_id %s (%d samples)",
+		speakerID, speakerName, uid, agentID, len(samples))
 	return nil
 }
 
+// ListSpeakerSamples 获取说话人的样本列表
+func (m *Manager) ListSpeakerSamples(uid, agentID, speakerID string) ([]*SampleInfo, error) {
+	if uid == "" {
+		return nil, newError(ErrInvalidArgument, "uid is required")
+	}
+
+	samples, err := m.vectorDB.ListSpeakerSamples(uid, agentID, speakerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list samples: %w", err)
+	}
+	if len(samples) == 0 {
+		return nil, newError(ErrNotFound, "speaker %s not found", speakerID)
+	}
+	return samples, nil
+}
+
+// DeleteSpeakerSample 删除说话人的单个样本（删除最后一个样本后说话人随之消失）
+func (m *Manager) DeleteSpeakerSample(uid, agentID, speakerID string, sampleIndex int) error {
+	if uid == "" {
+		return newError(ErrInvalidArgument, "uid is required")
+	}
+
+	if err := m.vectorDB.DeleteSample(uid, agentID, speakerID, sampleIndex); err != nil {
+		return fmt.Errorf("failed to delete from vector database: %w", err)
+	}
+
+	logger.Infof("Successfully deleted sample %d of speaker %s for uid %s, agent_id %s",
+		sampleIndex, speakerID, uid, agentID)
+	return nil
+}
+
+// AddSpeakerSample 为已注册的说话人追加样本（沿用已有样本的 speaker_name、uuid 和 agent_id）
+func (m *Manager) AddSpeakerSample(uid, agentID, speakerID string, audioData []float32, sampleRate int) (*SampleInfo, error) {
+	if uid == "" {
+		return nil, newError(ErrInvalidArgument, "uid is required")
+	}
+
+	embedding, err := m.extractEmbedding(audioData, sampleRate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract embedding: %w", err)
+	}
+
+	if len(embedding) != m.embeddingDim {
+		return nil, newError(ErrInvalidArgument, "embedding dimension mismatch: expected %d, got %d", m.embeddingDim, len(embedding))
+	}
+
+	unlock := m.lockSpeaker(uid, speakerID)
+	defer unlock()
+
+	samples, err := m.vectorDB.ListSpeakerSamples(uid, agentID, speakerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list samples: %w", err)
+	}
+	if len(samples) == 0 {
+		return nil, newError(ErrNotFound, "speaker %s not found", speakerID)
+	}
+	latest := samples[len(samples)-1]
+
+	duplicate, err := m.checkDuplicate(uid, latest.AgentID, speakerID, embedding)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := &SampleInfo{
+		SampleIndex: latest.SampleIndex + 1,
+		SpeakerID:   speakerID,
+		SpeakerName: latest.SpeakerName,
+		UUID:        latest.UUID,
+		AgentID:     latest.AgentID,
+		CreatedAt:   time.Unix(time.Now().Unix(), 0),
+		Duplicate:   duplicate,
+	}
+	sample.UpdatedAt = sample.CreatedAt
+
+	sample.PointID, err = m.vectorDB.Insert(uid, sample.AgentID, speakerID, sample.SpeakerName, sample.UUID, embedding,
+		sample.SampleIndex, sample.CreatedAt.Unix(), sample.UpdatedAt.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert to vector database: %w", err)
+	}
+
+	logger.Infof("Successfully added sample %d to speaker %s for uid %s, agent_id %s",
+		sample.SampleIndex, speakerID, uid, sample.AgentID)
+	return sample, nil
+}
+
 // IdentifySpeaker 识别声纹（支持可选的 UID、agent_id、speaker_id 和 speaker_name 过滤）
 // uid: 用户ID，如果为空字符串则不作为过滤条件
 // agentID: Agent ID，如果为空字符串则不作为过滤条件
 // speakerID: 说话人ID，如果为空字符串则不作为过滤条件
 // speakerName: 说话人名称，如果为空字符串则不作为过滤条件
-// threshold: 识别阈值，如果 <= 0 则使用默认阈值
-func (m *Manager) IdentifySpeaker(uid, agentID, speakerID, speakerName string, audioData []float32, sampleRate int, threshold ...float32) (*IdentifyResult, error) {
+// topK: 返回的候选说话人数量，如果 <= 0 则默认为 1
+// threshold: 识别阈值，如果 <= 0 则使用默认阈值（启用分数归一化时为归一化分数的阈值）
+func (m *Manager) IdentifySpeaker(uid, agentID, speakerID, speakerName string, audioData []float32, sampleRate int, topK int, threshold ...float32) (*IdentifyResult, error) {
 	// 确定使用的阈值：如果传入了有效的阈值（> 0），使用传入的；否则使用默认阈值
-	useThreshold := m.threshold
+	useThreshold := m.defaultThreshold()
 	if len(threshold) > 0 && threshold[0] > 0 {
 		useThreshold = threshold[0]
 	}
@@ -396,98 +969,268 @@ func (m *Manager) IdentifySpeaker(uid, agentID, speakerID, speakerName string, a
 	// 提取声纹特征
 	embedding, err := m.extractEmbedding(audioData, sampleRate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract embedding: %v", err)
+		return nil, fmt.Errorf("failed to extract embedding: %w", err)
 	}
 
-	// 在 Qdrant 向量数据库中搜索（按可选的 UID、agent_id、speaker_id 和 speaker_name 过滤，返回 top 1）
-	results, err := m.vectorDB.SearchWithOptionalFilters(uid, agentID, speakerID, speakerName, embedding, useThreshold, 1)
+	// 在 Qdrant 向量数据库中按说话人聚合搜索候选
+	candidates, err := m.rankCandidates(uid, agentID, speakerID, speakerName, embedding, useThreshold, topK)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search in vector database: %v", err)
+		return nil, err
 	}
 
-	result := &IdentifyResult{
-		Identified:  false,
-		SpeakerID:   "",
-		SpeakerName: "",
-		Confidence:  0.0,
-		Threshold:   useThreshold,
+	result := m.newIdentifyResult(candidates, useThreshold, normalizeTopK(topK))
+
+	// 与流式识别一样，自动注册前要求足够的净语音（测量失败时时长为 0，不会自动注册）
+	var speechDuration float64
+	if m.autoEnrollConfig.Enabled && m.streamingConfig.MinSpeechMs > 0 {
+		_, speechSamples, err := m.gateSpeech(audioData, sampleRate, 0)
+		if err != nil {
+			logger.Warnf("Failed to measure speech duration for auto-enrollment: %v", err)
+		}
+		speechDuration = float64(speechSamples) / float64(sampleRate)
+	}
+	m.autoEnroll(uid, agentID, speakerID, speakerName, embedding, speechDuration, result)
+	return result, nil
+}
+
+// normalizeTopK 规范化 topK 参数：<= 0 时默认为 1，且不超过 maxTopK
+func normalizeTopK(topK int) int {
+	if topK <= 0 {
+		return 1
+	}
+	if topK > maxTopK {
+		return maxTopK
+	}
+	return topK
+}
+
+// rankCandidates 按 speaker_id 聚合搜索，返回排序后的候选列表
+// 前 topK 个说话人无论是否超过阈值都会返回（低于阈值的标记为 rejected）；
+// 如果前 topK 个都超过阈值，则额外附加最近的一个低于阈值的说话人，便于展示"您是不是要找"
+// 启用分数归一化时按归一化分数重新排序，因此会多取一些说话人作为重排余量
+func (m *Manager) rankCandidates(uid, agentID, speakerID, speakerName string, embedding []float32, threshold float32, topK int) ([]Candidate, error) {
+	topK = normalizeTopK(topK)
+
+	// 多取一个说话人，用于在前 topK 全部通过时补充最近的被拒绝候选
+	fetch := topK + 1
+	if m.scoreNorm != nil {
+		fetch *= 2
+	}
+	scored, err := m.scoreSpeakers(uid, agentID, speakerID, speakerName, embedding, threshold, fetch)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(results) > 0 {
-		bestMatch := results[0]
+	candidates := make([]Candidate, 0, topK+1)
+	for i, candidate := range scored {
+		if i >= topK {
+			// 超出 topK 的候选只有在被拒绝且列表中还没有被拒绝候选时才保留
+			if !candidate.Rejected || (len(candidates) > 0 && candidates[len(candidates)-1].Rejected) {
+				break
+			}
+		}
+		candidate.Rank = i + 1
+		candidates = append(candidates, candidate)
+		if i >= topK {
+			break
+		}
+	}
+
+	return candidates, nil
+}
+
+// scoreSpeakers 按 speaker_id 聚合搜索最多 limit 个说话人，计算决策分数并按分数降序排列（不设置 Rank）
+func (m *Manager) scoreSpeakers(uid, agentID, speakerID, speakerName string, embedding []float32, threshold float32, limit int) ([]Candidate, error) {
+	return m.scoreSpeakersIn(m.vectorDB, uid, agentID, speakerID, speakerName, embedding, threshold, limit)
+}
+
+// scoreSpeakersIn 与 scoreSpeakers 相同，但在指定的 Collection 中检索（阈值校准使用临时 Collection）
+func (m *Manager) scoreSpeakersIn(db *QdrantVectorDB, uid, agentID, speakerID, speakerName string, embedding []float32, threshold float32, limit int) ([]Candidate, error) {
+	results, err := db.SearchGroupedBySpeaker(uid, agentID, speakerID, speakerName, embedding, limit, m.scoreNorm != nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search in vector database: %w", err)
+	}
+
+	testStats, err := m.testStats(embedding)
+	if err != nil {
+		return nil, err
+	}
+	scored := make([]Candidate, 0, len(results))
+	for _, r := range results {
+		score, normalized := m.scoreResult(r, testStats)
+		scored = append(scored, Candidate{
+			UID:             r.UID,
+			AgentID:         r.AgentID,
+			SpeakerID:       r.SpeakerID,
+			SpeakerName:     r.SpeakerName,
+			Confidence:      score,
+			RawScore:        r.Confidence,
+			NormalizedScore: normalized,
+			SampleIndex:     r.SampleIndex,
+			Rejected:        score < threshold,
+		})
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Confidence > scored[j].Confidence
+	})
+
+	return scored, nil
+}
+
+// newIdentifyResult 根据候选列表构建识别结果，排名第一且未被拒绝的候选即为识别结果
+func (m *Manager) newIdentifyResult(candidates []Candidate, threshold float32, topK int) *IdentifyResult {
+	result := &IdentifyResult{
+		Identified:         false,
+		SpeakerID:          "",
+		SpeakerName:        "",
+		Confidence:         0.0,
+		Threshold:          threshold,
+		ScoreNormalization: m.scoreNormMethod(),
+		TopK:               topK,
+		Candidates:         candidates,
+	}
+
+	if len(candidates) > 0 && !candidates[0].Rejected {
+		bestMatch := candidates[0]
 		result.Identified = true
+		result.UID = bestMatch.UID
+		result.AgentID = bestMatch.AgentID
 		result.SpeakerID = bestMatch.SpeakerID
 		result.SpeakerName = bestMatch.SpeakerName
 		result.Confidence = bestMatch.Confidence
+		result.RawScore = bestMatch.RawScore
+		result.NormalizedScore = bestMatch.NormalizedScore
 	}
 
-	return result, nil
+	return result
+}
+
+const (
+	// VerifyAggregationMax 取与所有样本相似度的最大值（默认）
+	VerifyAggregationMax = "max"
+	// VerifyAggregationMean 取与所有样本相似度的平均值
+	VerifyAggregationMean = "mean"
+	// VerifyAggregationCentroid 取与样本质心的相似度
+	VerifyAggregationCentroid = "centroid"
+)
+
+// VerifyOptions 声纹验证参数
+type VerifyOptions struct {
+	Threshold   float32 // 判定阈值，<= 0 时使用默认阈值
+	Aggregation string  // 多样本聚合方式：max（默认）、mean、centroid
+	TopK        int     // > 0 时额外返回该 UID/Agent 下排名前 TopK 的候选说话人，便于判断声称的说话人排在第几
 }
 
 // VerifySpeaker 验证声纹（支持 UID 和 Agent ID 维度隔离）
-func (m *Manager) VerifySpeaker(uid, agentID, speakerID string, audioData []float32, sampleRate int) (*VerifyResult, error) {
+// 对声称的说话人的全部样本打分并按 opts.Aggregation 聚合；无论是否通过都返回实际分数
+// 说话人不存在时返回 "speaker xxx not found" 错误
+func (m *Manager) VerifySpeaker(uid, agentID, speakerID string, audioData []float32, sampleRate int, opts VerifyOptions) (*VerifyResult, error) {
 	if uid == "" {
-		return nil, fmt.Errorf("uid is required")
+		return nil, newError(ErrInvalidArgument, "uid is required")
+	}
+	if opts.Aggregation == "" {
+		opts.Aggregation = VerifyAggregationMax
+	}
+	switch opts.Aggregation {
+	case VerifyAggregationMax, VerifyAggregationMean, VerifyAggregationCentroid:
+	default:
+		return nil, newError(ErrInvalidArgument, "invalid aggregation: %s", opts.Aggregation)
 	}
 
-	// 提取声纹特征
-	embedding, err := m.extractEmbedding(audioData, sampleRate)
+	// 先确认说话人存在，避免不存在的说话人也消耗一次特征提取
+	speaker, err := m.vectorDB.GetSpeakerEmbedding(uid, agentID, speakerID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract embedding: %v", err)
+		return nil, err
 	}
 
-	// 在 Qdrant 中搜索该 speaker 的所有样本
-	// Filter: uid = xxx AND agent_id = xxx AND speaker_id = xxx
-	results, err := m.vectorDB.SearchWithFilter(uid, agentID, speakerID, embedding, m.threshold, 1)
+	// 提取声纹特征
+	embedding, err := m.extractEmbedding(audioData, sampleRate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search in vector database: %v", err)
+		return nil, fmt.Errorf("failed to extract embedding: %w", err)
 	}
 
-	verified := len(results) > 0
-	confidence := float32(0.0)
-	speakerName := ""
+	useThreshold := m.defaultThreshold()
+	if opts.Threshold > 0 {
+		useThreshold = opts.Threshold
+	}
 
-	if verified {
-		confidence = results[0].Confidence
-		speakerName = results[0].SpeakerName
-	} else {
-		// 如果未找到，尝试获取 speaker 信息（验证是否存在）
-		speakerInfo, err := m.vectorDB.GetSpeakerInfo(uid, agentID, speakerID)
-		if err != nil {
-			return nil, fmt.Errorf("speaker %s not found", speakerID)
+	// 计算原始余弦相似度；enrollEmbedding 为分数归一化时代表注册侧的向量
+	query := normalizeVector(embedding)
+	var rawScore float32
+	var enrollEmbedding []float32
+	switch opts.Aggregation {
+	case VerifyAggregationCentroid:
+		normalized := make([][]float32, len(speaker.Embeddings))
+		for i, sample := range speaker.Embeddings {
+			normalized[i] = normalizeVector(sample)
+		}
+		enrollEmbedding = normalizeVector(meanVector(normalized))
+		rawScore = dotProduct(query, enrollEmbedding)
+	case VerifyAggregationMean:
+		normalized := make([][]float32, len(speaker.Embeddings))
+		var sum float32
+		for i, sample := range speaker.Embeddings {
+			normalized[i] = normalizeVector(sample)
+			sum += dotProduct(query, normalized[i])
+		}
+		rawScore = sum / float32(len(speaker.Embeddings))
+		enrollEmbedding = normalizeVector(meanVector(normalized))
+	default:
+		for i, sample := range speaker.Embeddings {
+			score := dotProduct(query, normalizeVector(sample))
+			if i == 0 || score > rawScore {
+				rawScore = score
+				enrollEmbedding = sample
+			}
 		}
-		speakerName = speakerInfo.Name
 	}
 
-	return &VerifyResult{
-		SpeakerID:   speakerID,
-		SpeakerName: speakerName,
-		Verified:    verified,
-		Confidence:  confidence,
-		Threshold:   m.threshold,
-	}, nil
-}
-
-// GetAllSpeakers 获取指定 UID 和 Agent ID 的所有注册的说话人
-func (m *Manager) GetAllSpeakers(uid, agentID string) []*SpeakerInfo {
-	speakers, err := m.vectorDB.GetAllSpeakers(uid, agentID)
+	testStats, err := m.testStats(embedding)
 	if err != nil {
-		logger.Errorf("Failed to get speakers from vector database: %v", err)
-		return []*SpeakerInfo{}
+		return nil, err
+	}
+	confidence, normalizedScore := m.scoreResult(SearchResult{
+		SpeakerID:   speakerID,
+		SpeakerName: speaker.SpeakerName,
+		Confidence:  rawScore,
+		Embedding:   enrollEmbedding,
+	}, testStats)
+
+	result := &VerifyResult{
+		SpeakerID:          speakerID,
+		SpeakerName:        speaker.SpeakerName,
+		Verified:           confidence >= useThreshold,
+		Confidence:         confidence,
+		RawScore:           rawScore,
+		NormalizedScore:    normalizedScore,
+		Threshold:          useThreshold,
+		ScoreNormalization: m.scoreNormMethod(),
+		Aggregation:        opts.Aggregation,
+		SampleCount:        len(speaker.Embeddings),
+	}
+
+	if opts.TopK > 0 {
+		candidates, err := m.rankCandidates(uid, agentID, "", "", embedding, useThreshold, opts.TopK)
+		if err != nil {
+			return nil, err
+		}
+		result.TopK = normalizeTopK(opts.TopK)
+		result.Candidates = candidates
 	}
-	return speakers
+
+	return result, nil
 }
 
 // DeleteSpeaker 删除说话人（支持 UID 和 Agent ID 维度隔离）
 func (m *Manager) DeleteSpeaker(uid, agentID, speakerID string) error {
 	if uid == "" {
-		return fmt.Errorf("uid is required")
+		return newError(ErrInvalidArgument, "uid is required")
 	}
 
 	// 从 Qdrant 向量数据库删除
 	err := m.vectorDB.DeleteSpeaker(uid, agentID, speakerID)
 	if err != nil {
-		return fmt.Errorf("failed to delete from vector database: %v", err)
+		return fmt.Errorf("failed to delete from vector database: %w", err)
 	}
 
 	logger.Infof("Successfully deleted speaker %s for uid %s, agent_id %s", speakerID, uid, agentID)
@@ -497,23 +1240,36 @@ func (m *Manager) DeleteSpeaker(uid, agentID, speakerID string) error {
 // DeleteSpeakerByUUID 通过 UUID 删除说话人（支持 UID 和 Agent ID 维度隔离）
 func (m *Manager) DeleteSpeakerByUUID(uid, agentID, uuid string) error {
 	if uid == "" {
-		return fmt.Errorf("uid is required")
+		return newError(ErrInvalidArgument, "uid is required")
 	}
 
 	if uuid == "" {
-		return fmt.Errorf("uuid is required")
+		return newError(ErrInvalidArgument, "uuid is required")
 	}
 
 	// 从 Qdrant 向量数据库删除
 	err := m.vectorDB.DeleteSpeakerByUUID(uid, agentID, uuid)
 	if err != nil {
-		return fmt.Errorf("failed to delete from vector database: %v", err)
+		return fmt.Errorf("failed to delete from vector database: %w", err)
 	}
 
 	logger.Infof("Successfully deleted speaker with uuid %s for uid %s, agent_id %s", uuid, uid, agentID)
 	return nil
 }
 
+// GetVectorDBStatus 获取 Qdrant 连接状态
+func (m *Manager) GetVectorDBStatus() *QdrantStatus {
+	return m.vectorDB.Status()
+}
+
+// GetScoreNormStatus 获取分数归一化状态，未启用时返回 nil
+func (m *Manager) GetScoreNormStatus() *ScoreNormStatus {
+	if m.scoreNorm == nil {
+		return nil
+	}
+	return m.scoreNorm.Status()
+}
+
 // GetStats 获取统计信息（用于主服务监控，支持按 UID 和 Agent ID 过滤）
 func (m *Manager) GetStats(uid, agentID string) map[string]interface{} {
 	stats := m.GetDatabaseStats(uid, agentID)
@@ -523,57 +1279,84 @@ func (m *Manager) GetStats(uid, agentID string) map[string]interface{} {
 		"total_samples": stats.TotalSamples,
 		"embedding_dim": stats.EmbeddingDim,
 		"threshold":     stats.Threshold,
+		"score_norm":    m.scoreNormMethod(),
 		"version":       stats.Version,
 		"last_updated":  stats.UpdatedAt.Format(time.RFC3339),
 	}
 }
 
-// GetDatabaseStats 获取数据库统计信息（支持按 UID 和 Agent ID 过滤）
+// GetDatabaseStats 获取数据库统计信息（支持按 UID 和 Agent ID 过滤，为空时返回全局统计）
+// 使用 Qdrant 的 Count 和 Facet 查询统计，不读取全部样本点
 func (m *Manager) GetDatabaseStats(uid, agentID string) *DatabaseStats {
 	// 从向量数据库获取统计信息
-	speakers, err := m.vectorDB.GetAllSpeakers(uid, agentID)
+	totalSamples, err := m.vectorDB.CountSamples(uid, agentID)
 	if err != nil {
-		logger.Errorf("Failed to get speakers from vector database: %v", err)
-		return &DatabaseStats{
-			TotalSpeakers: 0,
-			TotalSamples:  0,
-			EmbeddingDim:  m.embeddingDim,
-			Threshold:     m.threshold,
-			Version:       "2.0.0",
-			UpdatedAt:     time.Now(),
-		}
+		logger.Errorf("Failed to count samples in vector database: %v", err)
 	}
-
-	totalSamples := 0
-	for _, speaker := range speakers {
-		totalSamples += speaker.SampleCount
+	totalSpeakers, err := m.vectorDB.CountSpeakers(uid, agentID)
+	if err != nil {
+		logger.Errorf("Failed to count speakers in vector database: %v", err)
 	}
 
 	return &DatabaseStats{
-		TotalSpeakers: len(speakers),
+		TotalSpeakers: totalSpeakers,
 		TotalSamples:  totalSamples,
 		EmbeddingDim:  m.embeddingDim,
-		Threshold:     m.threshold,
+		Threshold:     m.GetThreshold(),
 		Version:       "2.0.0",
 		UpdatedAt:     time.Now(),
 	}
 }
 
 // 响应结构体定义
+// 注意：Confidence 为与阈值比较的决策分数，启用分数归一化时为归一化分数，否则为原始余弦相似度
 type IdentifyResult struct {
-	Identified  bool    `json:"identified"`
-	SpeakerID   string  `json:"speaker_id"`
-	SpeakerName string  `json:"speaker_name"`
-	Confidence  float32 `json:"confidence"`
-	Threshold   float32 `json:"threshold"`
+	Identified         bool        `json:"identified"`
+	UID                string      `json:"uid,omitempty"`      // 识别到的说话人所属的 uid
+	AgentID            string      `json:"agent_id,omitempty"` // 识别到的说话人所属的 agent_id
+	SpeakerID          string      `json:"speaker_id"`
+	SpeakerName        string      `json:"speaker_name"`
+	Confidence         float32     `json:"confidence"`
+	RawScore           float32     `json:"raw_score"`
+	NormalizedScore    *float32    `json:"normalized_score,omitempty"`
+	Threshold          float32     `json:"threshold"`
+	ScoreNormalization string      `json:"score_normalization,omitempty"`
+	TopK               int         `json:"top_k"`
+	Candidates         []Candidate `json:"candidates"`
+	SpeechDuration     float64     `json:"speech_duration,omitempty"` // 流式识别时 VAD 检测到的净语音时长（秒）
+
+	// 启用自动注册时：未识别的声音写入的匿名说话人，或命中匿名说话人时追加的样本
+	AutoEnrolled *AutoEnrollment `json:"auto_enrolled,omitempty"`
 }
 
 type VerifyResult struct {
-	SpeakerID   string  `json:"speaker_id"`
-	SpeakerName string  `json:"speaker_name"`
-	Verified    bool    `json:"verified"`
-	Confidence  float32 `json:"confidence"`
-	Threshold   float32 `json:"threshold"`
+	SpeakerID          string      `json:"speaker_id"`
+	SpeakerName        string      `json:"speaker_name"`
+	Verified           bool        `json:"verified"`
+	Confidence         float32     `json:"confidence"`
+	RawScore           float32     `json:"raw_score"`
+	NormalizedScore    *float32    `json:"normalized_score,omitempty"`
+	Threshold          float32     `json:"threshold"`
+	ScoreNormalization string      `json:"score_normalization,omitempty"`
+	Aggregation        string      `json:"aggregation"`  // 多样本聚合方式
+	SampleCount        int         `json:"sample_count"` // 参与打分的注册样本数
+	TopK               int         `json:"top_k,omitempty"`
+	Candidates         []Candidate `json:"candidates,omitempty"`
+}
+
+// Candidate 候选说话人（按 uid、agent_id、speaker_id 聚合，分数取该说话人最相似样本）
+// 不按 uid 或 agent_id 过滤时，同一个 speaker_id 可能以不同的 uid/agent_id 出现多次
+type Candidate struct {
+	Rank            int      `json:"rank"`
+	UID             string   `json:"uid"`
+	AgentID         string   `json:"agent_id"`
+	SpeakerID       string   `json:"speaker_id"`
+	SpeakerName     string   `json:"speaker_name"`
+	Confidence      float32  `json:"confidence"` // 决策分数
+	RawScore        float32  `json:"raw_score"`  // 原始余弦相似度
+	NormalizedScore *float32 `json:"normalized_score,omitempty"`
+	SampleIndex     int      `json:"sample_index"`
+	Rejected        bool     `json:"rejected"` // 决策分数低于阈值
 }
 
 type SpeakerInfo struct {
@@ -586,6 +1369,21 @@ type SpeakerInfo struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// SampleInfo 说话人单个样本的元数据
+type SampleInfo struct {
+	PointID     string    `json:"point_id"`
+	SampleIndex int       `json:"sample_index"`
+	SpeakerID   string    `json:"speaker_id"`
+	SpeakerName string    `json:"speaker_name"`
+	UUID        string    `json:"uuid"`
+	AgentID     string    `json:"agent_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// 仅在注册、追加样本时返回：重复声纹检测为 warn 模式且检测到重复
+	Duplicate *DuplicateMatch `json:"duplicate,omitempty"`
+}
+
 type DatabaseStats struct {
 	TotalSpeakers int       `json:"total_speakers"`
 	TotalSamples  int       `json:"total_samples"`
@@ -602,11 +1400,16 @@ type StreamingIdentifier struct {
 	agentID     string // Agent ID，如果为空字符串则不作为过滤条件
 	speakerID   string // 说话人ID，如果为空字符串则不作为过滤条件
 	speakerName string // 说话人名称，如果为空字符串则不作为过滤条件
-	stream      *sherpa.OnlineStream
+	audio       []float32 // 累积的音频数据（经 VAD 门控后只含语音及前后静音），完成时才从池中获取提取器，避免长连接占用提取器
 	sampleRate  int
 	threshold   float32 // 识别阈值，如果 <= 0 则使用默认阈值
+	topK        int     // 返回的候选说话人数量
 	mutex       sync.Mutex
 	isFinished  bool
+
+	gate         *speechGate // VAD 门控，收到第一块音频时创建，完成或关闭时归还 VAD 实例
+	gateDisabled bool        // 没有可用的 TEN-VAD，不做门控
+	totalSamples int         // 收到的原始样本数
 }
 
 // NewStreamingIdentifier 创建流式识别器（支持可选的 UID、agent_id、speaker_id 和 speaker_name 过滤）
@@ -614,10 +1417,10 @@ type StreamingIdentifier struct {
 // agentID: Agent ID，如果为空字符串则不作为过滤条件
 // speakerID: 说话人ID，如果为空字符串则不作为过滤条件
 // speakerName: 说话人名称，如果为空字符串则不作为过滤条件
+// topK: 返回的候选说话人数量，如果 <= 0 则默认为 1
 // threshold: 识别阈值，如果 <= 0 则使用默认阈值
-func (m *Manager) NewStreamingIdentifier(uid, agentID, speakerID, speakerName string, sampleRate int, threshold ...float32) *StreamingIdentifier {
-	stream := m.extractor.CreateStream()
-	useThreshold := m.threshold
+func (m *Manager) NewStreamingIdentifier(uid, agentID, speakerID, speakerName string, sampleRate int, topK int, threshold ...float32) *StreamingIdentifier {
+	useThreshold := m.defaultThreshold()
 	if len(threshold) > 0 && threshold[0] > 0 {
 		useThreshold = threshold[0]
 	}
@@ -627,9 +1430,10 @@ func (m *Manager) NewStreamingIdentifier(uid, agentID, speakerID, speakerName st
 		agentID:     agentID,
 		speakerID:   speakerID,
 		speakerName: speakerName,
-		stream:      stream,
+		audio:       make([]float32, 0),
 		sampleRate:  sampleRate,
 		threshold:   useThreshold,
+		topK:        normalizeTopK(topK),
 		isFinished:  false,
 	}
 }
@@ -640,16 +1444,37 @@ func (si *StreamingIdentifier) AcceptAudio(audioData []float32) error {
 	defer si.mutex.Unlock()
 
 	if si.isFinished {
-		return fmt.Errorf("stream already finished")
+		return newError(ErrInvalidArgument, "stream already finished")
 	}
 
-	if si.stream == nil {
-		return fmt.Errorf("stream is nil")
+	si.totalSamples += len(audioData)
+
+	// 经 VAD 门控后累积，只保留语音及前后静音
+	if si.gate == nil && !si.gateDisabled {
+		gate, err := si.manager.newSpeechGate(si.sampleRate, si.manager.streamingConfig.EdgePaddingMs)
+		if err != nil {
+			return err
+		}
+		si.gate = gate
+		si.gateDisabled = gate == nil
+	}
+	if si.gate == nil {
+		si.audio = append(si.audio, audioData...)
+		return nil
 	}
 
-	// 接受音频数据块
-	si.stream.AcceptWaveform(si.sampleRate, audioData)
-	return nil
+	var err error
+	si.audio, err = si.gate.process(audioData, si.audio)
+	return err
+}
+
+// speechDuration 净语音时长（秒），没有 VAD 门控时为收到的音频时长
+func (si *StreamingIdentifier) speechDuration() float64 {
+	samples := si.totalSamples
+	if si.gate != nil {
+		samples = si.gate.speechSamples
+	}
+	return float64(samples) / float64(si.sampleRate)
 }
 
 // FinishAndIdentify 完成输入并识别声纹
@@ -658,61 +1483,57 @@ func (si *StreamingIdentifier) FinishAndIdentify() (*IdentifyResult, error) {
 	defer si.mutex.Unlock()
 
 	if si.isFinished {
-		return nil, fmt.Errorf("stream already finished")
-	}
-
-	if si.stream == nil {
-		return nil, fmt.Errorf("stream is nil")
+		return nil, newError(ErrInvalidArgument, "stream already finished")
 	}
 
 	// 标记输入完成
-	si.stream.InputFinished()
 	si.isFinished = true
 
-	// 检查是否准备就绪
-	if !si.manager.extractor.IsReady(si.stream) {
+	// 处理最后不足一帧的音频，并检查净语音时长
+	if si.gate != nil {
+		var err error
+		if si.audio, err = si.gate.flush(si.audio); err != nil {
+			si.cleanup()
+			return nil, err
+		}
+	}
+	speechDuration := si.speechDuration()
+	minSpeech := float64(si.manager.streamingConfig.MinSpeechMs) / 1000
+	if minSpeech > 0 && speechDuration < minSpeech {
 		si.cleanup()
-		return nil, fmt.Errorf("insufficient audio data for embedding extraction")
+		return nil, newError(ErrInsufficientAudio, "insufficient speech: %.2fs detected, at least %.2fs required", speechDuration, minSpeech)
+	}
+	if len(si.audio) == 0 {
+		si.cleanup()
+		return nil, newError(ErrInsufficientAudio, "no speech detected")
 	}
 
 	// 提取特征
-	embedding := si.manager.extractor.Compute(si.stream)
-	if len(embedding) == 0 {
+	embedding, err := si.manager.extractEmbedding(si.audio, si.sampleRate)
+	if err != nil {
 		si.cleanup()
-		return nil, fmt.Errorf("failed to extract embedding")
+		return nil, err
 	}
 
 	// 确定使用的阈值：如果设置了自定义阈值则使用，否则使用默认阈值
-	useThreshold := si.manager.threshold
+	useThreshold := si.manager.defaultThreshold()
 	if si.threshold > 0 {
 		useThreshold = si.threshold
 	}
 
-	// 在 Qdrant 向量数据库中搜索（按可选的 UID、agent_id、speaker_id 和 speaker_name 过滤，返回 top 1）
-	results, err := si.manager.vectorDB.SearchWithOptionalFilters(si.uid, si.agentID, si.speakerID, si.speakerName, embedding, useThreshold, 1)
+	// 在 Qdrant 向量数据库中按说话人聚合搜索候选
+	candidates, err := si.manager.rankCandidates(si.uid, si.agentID, si.speakerID, si.speakerName, embedding, useThreshold, si.topK)
 	if err != nil {
 		si.cleanup()
-		return nil, fmt.Errorf("failed to search in vector database: %v", err)
+		return nil, err
 	}
 
-	//记录下results
-	logger.Debugf("Search results: %+v", results)
+	//记录下候选结果
+	logger.Debugf("Search candidates: %+v", candidates)
 
-	result := &IdentifyResult{
-		Identified:  false,
-		SpeakerID:   "",
-		SpeakerName: "",
-		Confidence:  0.0,
-		Threshold:   useThreshold,
-	}
-
-	if len(results) > 0 {
-		bestMatch := results[0]
-		result.Identified = true
-		result.SpeakerID = bestMatch.SpeakerID
-		result.SpeakerName = bestMatch.SpeakerName
-		result.Confidence = bestMatch.Confidence
-	}
+	result := si.manager.newIdentifyResult(candidates, useThreshold, si.topK)
+	result.SpeechDuration = speechDuration
+	si.manager.autoEnroll(si.uid, si.agentID, si.speakerID, si.speakerName, embedding, speechDuration, result)
 
 	// 清理资源
 	si.cleanup()
@@ -720,11 +1541,11 @@ func (si *StreamingIdentifier) FinishAndIdentify() (*IdentifyResult, error) {
 	return result, nil
 }
 
-// cleanup 清理资源
+// cleanup 清理资源并归还 VAD 实例
 func (si *StreamingIdentifier) cleanup() {
-	if si.stream != nil {
-		sherpa.DeleteOnlineStream(si.stream)
-		si.stream = nil
+	si.audio = nil
+	if si.gate != nil {
+		si.gate.close()
 	}
 }
 
@@ -734,3 +1555,8 @@ func (si *StreamingIdentifier) Close() {
 	defer si.mutex.Unlock()
 	si.cleanup()
 }
+
+// GetExtractorPoolStats 获取声纹特征提取器池统计信息
+func (m *Manager) GetExtractorPoolStats() map[string]interface{} {
+	return m.extractorPool.GetStats()
+}
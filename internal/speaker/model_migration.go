@@ -0,0 +1,251 @@
+package speaker
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"voice_server/internal/logger"
+)
+
+// legacyAudioWindowSeconds 旧版本在写入样本之后才保存注册音频，文件名时间戳相对样本时间的最大延迟
+const legacyAudioWindowSeconds = 5
+
+// ModelMigrationOptions 模型迁移参数
+type ModelMigrationOptions struct {
+	SourceCollection string // 旧模型的 Collection（或别名）
+	AudioDir         string // 音频归档根目录（递归查找注册音频）
+	AllowMissing     bool   // 允许丢弃找不到注册音频的样本
+	DryRun           bool   // 只检查注册音频是否齐全，不写入
+}
+
+// ModelMigrationReport 模型迁移结果
+type ModelMigrationReport struct {
+	SourceCollection string   `json:"source_collection"`
+	TargetCollection string   `json:"target_collection"`
+	Model            string   `json:"model"`
+	EmbeddingDim     int      `json:"embedding_dim"`
+	SourcePoints     int      `json:"source_points"`
+	Migrated         int      `json:"migrated"`
+	LegacyMatched    int      `json:"legacy_matched"`          // 按 uid/agent_id/时间戳匹配到旧版本平铺音频的样本数
+	MissingAudio     []string `json:"missing_audio,omitempty"` // 找不到注册音频的 Point ID
+	Failed           []string `json:"failed,omitempty"`        // 注册音频无法提取声纹的 Point ID
+	DryRun           bool     `json:"dry_run"`
+}
+
+// MigrateModel 使用当前模型从注册音频重新提取声纹，写入当前 Collection（迁移目标）
+// 迁移目标必须为空；Point ID 和 payload 沿用源 Collection，model 字段改为当前模型
+// 切换到新 Collection（别名或配置）由调用方在迁移成功后完成
+func (m *Manager) MigrateModel(opts ModelMigrationOptions) (*ModelMigrationReport, error) {
+	if opts.SourceCollection == "" {
+		return nil, fmt.Errorf("source collection is required")
+	}
+	if opts.SourceCollection == m.vectorDB.collectionName {
+		return nil, fmt.Errorf("source and target collection must be different")
+	}
+
+	report := &ModelMigrationReport{
+		SourceCollection: opts.SourceCollection,
+		TargetCollection: m.vectorDB.collectionName,
+		Model:            m.modelName,
+		EmbeddingDim:     m.embeddingDim,
+		DryRun:           opts.DryRun,
+	}
+
+	existing, err := m.vectorDB.CountSamples("", "")
+	if err != nil {
+		return nil, err
+	}
+	if existing > 0 {
+		return nil, fmt.Errorf("target collection '%s' is not empty (%d points)", m.vectorDB.collectionName, existing)
+	}
+
+	source := m.vectorDB.withCollection(opts.SourceCollection)
+	points, err := source.ExportPoints("", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source collection: %w", err)
+	}
+	report.SourcePoints = len(points)
+
+	index, err := indexRegisterAudio(opts.AudioDir)
+	if err != nil {
+		return nil, err
+	}
+	audioFiles, legacyMatched := index.match(points)
+	report.LegacyMatched = legacyMatched
+
+	// 先检查注册音频是否齐全，避免迁移到一半才发现缺失
+	for _, point := range points {
+		if _, ok := audioFiles[point.ID]; !ok {
+			report.MissingAudio = append(report.MissingAudio, point.ID)
+		}
+	}
+	if len(report.MissingAudio) > 0 && !opts.AllowMissing {
+		return report, fmt.Errorf("%d of %d points have no archived enrollment audio", len(report.MissingAudio), len(points))
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+
+	migrated := make([]StoredPoint, 0, len(points))
+	for _, point := range points {
+		path, ok := audioFiles[point.ID]
+		if !ok {
+			continue
+		}
+
+		embedding, err := m.embedAudioFile(path)
+		if err != nil {
+			logger.Warnf("Failed to re-embed %s for point %s: %v", path, point.ID, err)
+			report.Failed = append(report.Failed, point.ID)
+			continue
+		}
+
+		payload := make(map[string]any, len(point.Payload))
+		for k, v := range point.Payload {
+			payload[k] = v
+		}
+		payload["model"] = m.modelName
+		migrated = append(migrated, StoredPoint{ID: point.ID, Payload: payload, Vector: embedding})
+	}
+	if len(report.Failed) > 0 && !opts.AllowMissing {
+		return report, fmt.Errorf("failed to re-embed %d points", len(report.Failed))
+	}
+
+	if err := m.vectorDB.InsertPoints(migrated); err != nil {
+		return report, fmt.Errorf("failed to write target collection: %w", err)
+	}
+
+	// 校验写入数量
+	count, err := m.vectorDB.CountSamples("", "")
+	if err != nil {
+		return report, err
+	}
+	if count != len(migrated) {
+		return report, fmt.Errorf("target collection has %d points, expected %d", count, len(migrated))
+	}
+	report.Migrated = count
+
+	logger.Infof("✅ Migrated %d/%d points from '%s' to '%s' with model %s",
+		report.Migrated, report.SourcePoints, report.SourceCollection, report.TargetCollection, m.modelName)
+	return report, nil
+}
+
+// embedAudioFile 读取注册音频并提取声纹（注册音频保存前已过滤静音）
+func (m *Manager) embedAudioFile(path string) ([]float32, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	audioData, sampleRate, err := decodeWAV(file)
+	if err != nil {
+		return nil, err
+	}
+	return m.extractEmbedding(audioData, sampleRate)
+}
+
+// registerAudioIndex 注册音频索引
+type registerAudioIndex struct {
+	byPointID map[string]string // 文件名以 _<point_id>.wav 结尾的注册音频
+	byName    map[string]string // 文件名到路径，用于匹配旧版本平铺保存、不含 Point ID 的注册音频
+}
+
+// indexRegisterAudio 递归扫描注册音频目录（归档目录和旧版本平铺目录都适用）
+func indexRegisterAudio(dir string) (*registerAudioIndex, error) {
+	index := &registerAudioIndex{byPointID: make(map[string]string), byName: make(map[string]string)}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() || !strings.HasPrefix(name, "register_") || !strings.HasSuffix(name, ".wav") {
+			return nil
+		}
+		index.byName[name] = path
+		base := strings.TrimSuffix(name, ".wav")
+		if i := strings.LastIndex(base, "_"); i >= 0 {
+			index.byPointID[base[i+1:]] = path
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan audio directory: %w", err)
+	}
+	return index, nil
+}
+
+// match 为每个样本点查找注册音频，返回 Point ID 到文件路径的映射和按旧版本文件名匹配到的数量
+// 依次按 Point ID、导入前的原 Point ID（source_point_id）匹配；都找不到时按 uid/agent_id 和样本时间匹配旧版本平铺的文件，
+// 同一个旧文件被多个样本匹配到时无法确定归属，这些样本都视为缺失
+func (idx *registerAudioIndex) match(points []StoredPoint) (map[string]string, int) {
+	matched := make(map[string]string, len(points))
+	claims := make(map[string][]string) // 旧文件名 -> 匹配到它的 Point ID
+	for _, point := range points {
+		if path, ok := idx.byPointID[point.ID]; ok {
+			matched[point.ID] = path
+			continue
+		}
+		if sourceID := payloadString(point.Payload, "source_point_id"); sourceID != "" {
+			if path, ok := idx.byPointID[sourceID]; ok {
+				matched[point.ID] = path
+				continue
+			}
+		}
+		if name := idx.legacyName(point.Payload); name != "" {
+			claims[name] = append(claims[name], point.ID)
+		}
+	}
+
+	legacy := 0
+	for name, ids := range claims {
+		if len(ids) > 1 {
+			logger.Warnf("Legacy enrollment audio %s matches %d points, skipping: %v", name, len(ids), ids)
+			continue
+		}
+		matched[ids[0]] = idx.byName[name]
+		legacy++
+	}
+	return matched, legacy
+}
+
+// legacyName 按旧版本的命名规则 register_<本地时间>_<uid>_<agent_id>.wav 查找样本对应的注册音频
+// 旧版本在写入样本后才保存音频，文件时间戳不早于样本的 updated_at/created_at（需在与旧服务相同的时区运行）
+func (idx *registerAudioIndex) legacyName(payload map[string]any) string {
+	uid := payloadString(payload, "uid")
+	agentID := payloadString(payload, "agent_id")
+	var tenant string
+	switch {
+	case uid != "" && agentID != "":
+		tenant = "_" + uid + "_" + agentID
+	case uid != "":
+		tenant = "_" + uid
+	case agentID != "":
+		tenant = "_" + agentID
+	}
+
+	// 追加样本时 created_at 沿用说话人的创建时间，updated_at 才是样本写入时间
+	for _, key := range []string{"updated_at", "created_at"} {
+		at := payloadInt(payload, key)
+		if at <= 0 {
+			continue
+		}
+		for offset := int64(0); offset <= legacyAudioWindowSeconds; offset++ {
+			timestamp := time.Unix(at+offset, 0).Format("20060102_150405")
+			name := sanitizeFileComponent("register_" + timestamp + tenant + ".wav")
+			if _, ok := idx.byName[name]; ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// SwitchCollectionAlias 将 Qdrant 别名原子地切换到当前 Collection
+func (m *Manager) SwitchCollectionAlias(alias string) error {
+	return m.vectorDB.SwitchAlias(alias, m.vectorDB.collectionName)
+}
@@ -0,0 +1,259 @@
+package speaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// QdrantStateReady 已连接且 Collection 已初始化
+	QdrantStateReady = "ready"
+	// QdrantStateConnecting 启动时未能连接，后台重连中
+	QdrantStateConnecting = "connecting"
+	// QdrantStateDegraded 熔断器打开，请求直接失败，冷却后放行一个探测请求
+	QdrantStateDegraded = "degraded"
+
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half_open"
+)
+
+// QdrantResilienceConfig Qdrant 调用的超时、重试和熔断配置
+type QdrantResilienceConfig struct {
+	TimeoutMs         int // 单次调用超时（调用方未设置截止时间时生效），默认 5000
+	MaxRetries        int // 连接类错误的重试次数，默认 2，-1 表示不重试
+	RetryBackoffMs    int // 首次重试等待时间，之后每次翻倍，默认 200
+	BreakerThreshold  int // 连续失败多少次后打开熔断器，默认 5
+	BreakerCooldownMs int // 熔断器打开后多久放行探测请求，默认 10000
+}
+
+// QdrantStatus Qdrant 连接状态（用于健康检查）
+type QdrantStatus struct {
+	State               string     `json:"state"`
+	Breaker             string     `json:"breaker"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+}
+
+// qdrantInitKey 标记初始化阶段的调用，未就绪时只放行这些调用
+type qdrantInitKey struct{}
+
+// qdrantGuard 通过 gRPC 拦截器为所有 Qdrant 调用加上超时、重试和熔断
+type qdrantGuard struct {
+	timeout          time.Duration
+	maxRetries       int
+	backoff          time.Duration
+	breakerThreshold int
+	breakerCooldown  time.Duration
+
+	// Collection 初始化完成前拒绝普通调用
+	ready atomic.Bool
+
+	mu            sync.Mutex
+	breaker       string
+	failures      int
+	openUntil     time.Time
+	probing       bool // 半开状态下已有探测请求在进行
+	lastError     string
+	lastErrorAt   time.Time
+	lastSuccessAt time.Time
+}
+
+// newQdrantGuard 创建调用保护
+func newQdrantGuard(cfg QdrantResilienceConfig) *qdrantGuard {
+	if cfg.TimeoutMs <= 0 {
+		cfg.TimeoutMs = 5000
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 2
+	}
+	if cfg.RetryBackoffMs <= 0 {
+		cfg.RetryBackoffMs = 200
+	}
+	if cfg.BreakerThreshold <= 0 {
+		cfg.BreakerThreshold = 5
+	}
+	if cfg.BreakerCooldownMs <= 0 {
+		cfg.BreakerCooldownMs = 10000
+	}
+	return &qdrantGuard{
+		timeout:          time.Duration(cfg.TimeoutMs) * time.Millisecond,
+		maxRetries:       cfg.MaxRetries,
+		backoff:          time.Duration(cfg.RetryBackoffMs) * time.Millisecond,
+		breakerThreshold: cfg.BreakerThreshold,
+		breakerCooldown:  time.Duration(cfg.BreakerCooldownMs) * time.Millisecond,
+		breaker:          breakerClosed,
+	}
+}
+
+// unaryInterceptor gRPC 一元调用拦截器
+func (g *qdrantGuard) unaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if !g.ready.Load() && ctx.Value(qdrantInitKey{}) == nil {
+		return storeUnavailable("vector database not ready: %s", g.lastErrorMessage())
+	}
+	if err := g.allow(); err != nil {
+		return err
+	}
+
+	var err error
+	backoff := g.backoff
+	for attempt := 0; ; attempt++ {
+		callCtx, cancel := ctx, context.CancelFunc(func() {})
+		if _, ok := ctx.Deadline(); !ok {
+			callCtx, cancel = context.WithTimeout(ctx, g.timeout)
+		}
+		err = invoker(callCtx, method, req, reply, cc, opts...)
+		cancel()
+
+		if err == nil || !isTransientQdrantError(err) || attempt >= g.maxRetries || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		backoff *= 2
+	}
+
+	g.record(err)
+	return err
+}
+
+// allow 熔断器打开时直接拒绝；冷却结束后只放行一个探测请求
+func (g *qdrantGuard) allow() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch g.breaker {
+	case breakerOpen:
+		if time.Now().Before(g.openUntil) {
+			return storeUnavailable("vector database unavailable (circuit open): %s", g.lastError)
+		}
+		g.breaker = breakerHalfOpen
+		g.probing = true
+	case breakerHalfOpen:
+		if g.probing {
+			return storeUnavailable("vector database unavailable (circuit half-open): %s", g.lastError)
+		}
+		g.probing = true
+	}
+	return nil
+}
+
+// record 记录调用结果；只有连接类错误计入熔断，业务错误（如 NotFound）说明 Qdrant 可用
+func (g *qdrantGuard) record(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err == nil || !isTransientQdrantError(err) {
+		g.breaker = breakerClosed
+		g.failures = 0
+		g.probing = false
+		g.lastSuccessAt = time.Now()
+		return
+	}
+
+	g.failures++
+	g.lastError = err.Error()
+	g.lastErrorAt = time.Now()
+	if g.breaker == breakerHalfOpen || g.failures >= g.breakerThreshold {
+		g.breaker = breakerOpen
+		g.openUntil = time.Now().Add(g.breakerCooldown)
+	}
+	g.probing = false
+}
+
+// recordInitError 记录后台初始化失败的原因（非连接类错误不会经过拦截器的统计）
+func (g *qdrantGuard) recordInitError(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.lastError = err.Error()
+	g.lastErrorAt = time.Now()
+}
+
+// lastErrorMessage 返回最近一次错误
+func (g *qdrantGuard) lastErrorMessage() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.lastError == "" {
+		return "connecting"
+	}
+	return g.lastError
+}
+
+// status 返回连接状态
+func (g *qdrantGuard) status() *QdrantStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	s := &QdrantStatus{
+		State:               QdrantStateReady,
+		Breaker:             g.breaker,
+		ConsecutiveFailures: g.failures,
+		LastError:           g.lastError,
+	}
+	switch {
+	case !g.ready.Load():
+		s.State = QdrantStateConnecting
+	case g.breaker != breakerClosed:
+		s.State = QdrantStateDegraded
+	}
+	if !g.lastErrorAt.IsZero() {
+		t := g.lastErrorAt
+		s.LastErrorAt = &t
+	}
+	if !g.lastSuccessAt.IsZero() {
+		t := g.lastSuccessAt
+		s.LastSuccessAt = &t
+	}
+	return s
+}
+
+// storeUnavailableError 调用保护直接拒绝的错误，errors.Is 匹配 ErrStoreUnavailable，同时保留 Unavailable 状态码
+type storeUnavailableError struct {
+	st *status.Status
+}
+
+func storeUnavailable(format string, args ...any) error {
+	return &storeUnavailableError{st: status.Newf(codes.Unavailable, format, args...)}
+}
+
+func (e *storeUnavailableError) Error() string { return e.st.Err().Error() }
+
+func (e *storeUnavailableError) GRPCStatus() *status.Status { return e.st }
+
+func (e *storeUnavailableError) Unwrap() error { return ErrStoreUnavailable }
+
+// isTransientQdrantError 判断是否为连接类错误（可重试，计入熔断）
+func isTransientQdrantError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// reconnectBackoff 后台重连的等待时间（1s 起每次翻倍，最长 30s）
+func reconnectBackoff(attempt int) time.Duration {
+	wait := time.Second << min(attempt, 5)
+	return min(wait, 30*time.Second)
+}
+
+// initContext 初始化阶段使用的上下文
+func initContext() context.Context {
+	return context.WithValue(context.Background(), qdrantInitKey{}, true)
+}
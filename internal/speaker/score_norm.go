@@ -0,0 +1,290 @@
+package speaker
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"voice_server/internal/logger"
+)
+
+const (
+	// ScoreNormSNorm 对称归一化（使用全部冒名者集合计算统计量）
+	ScoreNormSNorm = "snorm"
+	// ScoreNormASNorm 自适应对称归一化（仅使用与被测向量最相似的 top-N 冒名者计算统计量）
+	ScoreNormASNorm = "asnorm"
+
+	// defaultCohortTopN AS-norm 默认使用的冒名者数量
+	defaultCohortTopN = 200
+	// minCohortStd 标准差下限，避免除零
+	minCohortStd = 1e-6
+)
+
+// ScoreNormConfig 分数归一化配置
+type ScoreNormConfig struct {
+	Enabled          bool    `json:"enabled"`
+	Method           string  `json:"method"`            // snorm 或 asnorm，默认 asnorm
+	CohortDir        string  `json:"cohort_dir"`        // 冒名者向量目录（.json 或 .f32 文件）
+	CohortCollection string  `json:"cohort_collection"` // 冒名者向量所在的 Qdrant Collection（与 cohort_dir 二选一）
+	TopN             int     `json:"top_n"`             // AS-norm 使用的冒名者数量，默认 200
+	Threshold        float32 `json:"threshold"`         // 归一化分数的判定阈值
+}
+
+// cohortStats 向量相对冒名者集合的得分统计量
+type cohortStats struct {
+	mean float32
+	std  float32
+}
+
+// ScoreNormStatus 分数归一化状态（健康检查）
+type ScoreNormStatus struct {
+	Method     string `json:"method"`
+	Source     string `json:"source"` // cohort_dir 或 cohort_collection
+	Loaded     bool   `json:"loaded"` // 冒名者集合是否已加载，未加载时声纹识别和验证会失败
+	CohortSize int    `json:"cohort_size"`
+	LastError  string `json:"last_error,omitempty"`
+}
+
+// ScoreNormalizer 基于冒名者集合（impostor cohort）的分数归一化器
+type ScoreNormalizer struct {
+	method       string
+	topN         int
+	threshold    float32
+	embeddingDim int
+	source       string
+
+	// 冒名者集合：cohort_dir 在创建时加载；cohort_collection 依赖 Qdrant，在首次使用时加载，失败后下次使用时重试
+	mu         sync.RWMutex
+	cohort     [][]float32 // 已 L2 归一化的冒名者向量，加载成功后不再修改
+	loadCohort func() ([][]float32, error)
+	lastError  string
+}
+
+// NewScoreNormalizer 创建分数归一化器并加载冒名者集合
+// cohort_collection 的冒名者集合延迟加载，Qdrant 不可用时不影响声纹模块启动
+func NewScoreNormalizer(cfg *ScoreNormConfig, embeddingDim int, vectorDB *QdrantVectorDB) (*ScoreNormalizer, error) {
+	method := strings.ToLower(cfg.Method)
+	if method == "" {
+		method = ScoreNormASNorm
+	}
+	if method != ScoreNormSNorm && method != ScoreNormASNorm {
+		return nil, fmt.Errorf("unsupported score normalization method: %s", cfg.Method)
+	}
+
+	topN := cfg.TopN
+	if topN <= 0 {
+		topN = defaultCohortTopN
+	}
+
+	n := &ScoreNormalizer{
+		method:       method,
+		topN:         topN,
+		threshold:    cfg.Threshold,
+		embeddingDim: embeddingDim,
+	}
+	switch {
+	case cfg.CohortDir != "":
+		n.source = "cohort_dir"
+		n.loadCohort = func() ([][]float32, error) { return loadCohortFromDir(cfg.CohortDir, embeddingDim) }
+		if err := n.ensureCohort(); err != nil {
+			return nil, err
+		}
+	case cfg.CohortCollection != "":
+		n.source = "cohort_collection"
+		collection := cfg.CohortCollection
+		n.loadCohort = func() ([][]float32, error) { return vectorDB.LoadCollectionVectors(collection) }
+		logger.Infof("Impostor cohort will be loaded from collection '%s' on first use", collection)
+	default:
+		return nil, fmt.Errorf("score normalization requires cohort_dir or cohort_collection")
+	}
+
+	logger.Infof("✅ Score normalization enabled: method=%s, top_n=%d, threshold=%.4f", method, topN, cfg.Threshold)
+	return n, nil
+}
+
+// ensureCohort 加载冒名者集合（已加载时直接返回）
+func (n *ScoreNormalizer) ensureCohort() error {
+	n.mu.RLock()
+	loaded := n.cohort != nil
+	n.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.cohort != nil {
+		return nil
+	}
+
+	cohort, err := n.loadCohort()
+	if err == nil {
+		cohort, err = prepareCohort(cohort, n.embeddingDim)
+	}
+	if err != nil {
+		n.lastError = err.Error()
+		return fmt.Errorf("failed to load impostor cohort: %w", err)
+	}
+	n.cohort = cohort
+	n.lastError = ""
+	logger.Infof("✅ Impostor cohort loaded from %s: cohort_size=%d", n.source, len(cohort))
+	return nil
+}
+
+// prepareCohort 校验冒名者向量维度并做 L2 归一化
+func prepareCohort(cohort [][]float32, embeddingDim int) ([][]float32, error) {
+	normalized := make([][]float32, 0, len(cohort))
+	for i, vector := range cohort {
+		if len(vector) != embeddingDim {
+			return nil, fmt.Errorf("cohort embedding %d dimension mismatch: expected %d, got %d", i, embeddingDim, len(vector))
+		}
+		normalized = append(normalized, normalizeVector(vector))
+	}
+	if len(normalized) < 2 {
+		return nil, fmt.Errorf("impostor cohort too small: %d embeddings", len(normalized))
+	}
+	return normalized, nil
+}
+
+// loadCohortFromDir 从目录加载冒名者向量
+// 支持的文件格式：
+//   - .json：单个向量 [0.1, ...] 或向量数组 [[0.1, ...], ...]
+//   - .f32：小端 float32 原始数据，长度必须是维度的整数倍
+func loadCohortFromDir(dir string, embeddingDim int) ([][]float32, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cohort directory: %w", err)
+	}
+
+	cohort := make([][]float32, 0)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".json":
+			var many [][]float32
+			if err := json.Unmarshal(data, &many); err == nil {
+				cohort = append(cohort, many...)
+				continue
+			}
+			var single []float32
+			if err := json.Unmarshal(data, &single); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			cohort = append(cohort, single)
+		case ".f32":
+			if len(data)%(4*embeddingDim) != 0 {
+				return nil, fmt.Errorf("invalid size of %s: %d bytes is not a multiple of %d", path, len(data), 4*embeddingDim)
+			}
+			for offset := 0; offset < len(data); offset += 4 * embeddingDim {
+				vector := make([]float32, embeddingDim)
+				for i := range vector {
+					bits := binary.LittleEndian.Uint32(data[offset+i*4 : offset+(i+1)*4])
+					vector[i] = math.Float32frombits(bits)
+				}
+				cohort = append(cohort, vector)
+			}
+		default:
+			logger.Debugf("Skipping non-embedding file in cohort directory: %s", path)
+		}
+	}
+
+	return cohort, nil
+}
+
+// Method 返回归一化方法名称
+func (n *ScoreNormalizer) Method() string {
+	return n.method
+}
+
+// Threshold 返回归一化分数的默认判定阈值
+func (n *ScoreNormalizer) Threshold() float32 {
+	return n.threshold
+}
+
+// CohortSize 返回冒名者集合大小（未加载时为 0）
+func (n *ScoreNormalizer) CohortSize() int {
+	return len(n.loadedCohort())
+}
+
+// Status 返回冒名者集合的加载状态
+func (n *ScoreNormalizer) Status() *ScoreNormStatus {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return &ScoreNormStatus{
+		Method:     n.method,
+		Source:     n.source,
+		Loaded:     n.cohort != nil,
+		CohortSize: len(n.cohort),
+		LastError:  n.lastError,
+	}
+}
+
+// loadedCohort 返回已加载的冒名者集合
+func (n *ScoreNormalizer) loadedCohort() [][]float32 {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.cohort
+}
+
+// stats 计算向量相对冒名者集合的得分均值和标准差
+// S-norm 使用全部冒名者，AS-norm 仅使用得分最高的 top-N 个冒名者；调用前需已通过 ensureCohort 加载冒名者集合
+func (n *ScoreNormalizer) stats(embedding []float32) cohortStats {
+	query := normalizeVector(embedding)
+	cohort := n.loadedCohort()
+	scores := make([]float32, len(cohort))
+	for i, impostor := range cohort {
+		scores[i] = dotProduct(query, impostor)
+	}
+
+	if n.method == ScoreNormASNorm && n.topN < len(scores) {
+		sort.Slice(scores, func(i, j int) bool { return scores[i] > scores[j] })
+		scores = scores[:n.topN]
+	}
+
+	var sum float64
+	for _, score := range scores {
+		sum += float64(score)
+	}
+	mean := sum / float64(len(scores))
+
+	var variance float64
+	for _, score := range scores {
+		diff := float64(score) - mean
+		variance += diff * diff
+	}
+	std := math.Sqrt(variance / float64(len(scores)))
+	if std < minCohortStd {
+		std = minCohortStd
+	}
+
+	return cohortStats{mean: float32(mean), std: float32(std)}
+}
+
+// normalize 对原始余弦分数进行对称归一化
+// s_norm = 0.5 * ((s - μ_e) / σ_e + (s - μ_t) / σ_t)
+func (n *ScoreNormalizer) normalize(raw float32, enroll, test cohortStats) float32 {
+	return 0.5 * ((raw-enroll.mean)/enroll.std + (raw-test.mean)/test.std)
+}
+
+// dotProduct 计算两个向量的点积（向量已归一化时即为余弦相似度）
+func dotProduct(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
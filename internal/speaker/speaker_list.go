@@ -0,0 +1,485 @@
+package speaker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+const (
+	// 说话人列表支持的排序字段
+	SpeakerSortCreatedAt   = "created_at"
+	SpeakerSortUpdatedAt   = "updated_at"
+	SpeakerSortName        = "name"
+	SpeakerSortSampleCount = "sample_count"
+	SpeakerSortID          = "id"
+
+	// maxSpeakerPageSize 单页最大说话人数量
+	maxSpeakerPageSize = 1000
+)
+
+// SpeakerListOptions 说话人列表的过滤、排序和分页参数
+type SpeakerListOptions struct {
+	NamePrefix    string    // 名称前缀过滤（为空则不过滤）
+	CreatedAfter  time.Time // 创建时间下限（含，零值不过滤）
+	CreatedBefore time.Time // 创建时间上限（不含，零值不过滤）
+	MinSamples    int       // 最少样本数（<= 0 则不过滤）
+	SortBy        string    // 排序字段，默认 created_at
+	Desc          bool      // 是否降序
+	Limit         int       // 每页数量（<= 0 则返回全部）
+	Cursor        string    // 上一页返回的 next_cursor
+}
+
+// SpeakerPage 说话人列表分页结果
+type SpeakerPage struct {
+	Speakers   []*SpeakerInfo `json:"speakers"`
+	Total      *int           `json:"total,omitempty"` // 满足过滤条件的说话人总数，按名称前缀或创建时间过滤时不计算（需要读取全部说话人）
+	NextCursor string         `json:"next_cursor,omitempty"`
+	HasMore    bool           `json:"has_more"`
+}
+
+// speakerCursor 游标内容：记录上一页最后一个说话人的排序键
+// 使用排序键而不是偏移量，翻页期间有说话人新增或删除时不会重复或遗漏
+type speakerCursor struct {
+	SortBy      string `json:"s"`
+	Desc        bool   `json:"d"`
+	ID          string `json:"id"`
+	AgentID     string `json:"a,omitempty"`
+	Name        string `json:"n,omitempty"`
+	SampleCount int    `json:"c,omitempty"`
+	CreatedAt   int64  `json:"ca,omitempty"`
+	UpdatedAt   int64  `json:"ua,omitempty"`
+}
+
+const (
+	// speakerFetchBatch 每次读取详情的说话人（或名称）数量
+	speakerFetchBatch = 100
+	// speakerScrollBatch 按时间排序时每次读取的样本点数量
+	speakerScrollBatch = 1000
+)
+
+// ListSpeakers 按条件分页获取说话人列表，每页只读取候选说话人的样本点：
+// - 按 id、sample_count 排序：Facet 聚合得到全部说话人及样本数（每个说话人一行），排序后从游标处分批读取详情
+// - 按 name 排序：Facet 聚合得到全部名称，从游标处分批读取这些名称下的说话人
+// - 按 created_at、updated_at 排序：按该字段顺序读取样本点流，从游标的取值处开始，凑够一页即停止
+func (m *Manager) ListSpeakers(uid, agentID string, opts SpeakerListOptions) (*SpeakerPage, error) {
+	if uid == "" {
+		return nil, newError(ErrInvalidArgument, "uid is required")
+	}
+
+	if opts.SortBy == "" {
+		opts.SortBy = SpeakerSortCreatedAt
+	}
+	switch opts.SortBy {
+	case SpeakerSortCreatedAt, SpeakerSortUpdatedAt, SpeakerSortName, SpeakerSortSampleCount, SpeakerSortID:
+	default:
+		return nil, newError(ErrInvalidArgument, "invalid sort field: %s", opts.SortBy)
+	}
+	if opts.Limit > maxSpeakerPageSize {
+		opts.Limit = maxSpeakerPageSize
+	}
+
+	l := &speakerLister{db: m.vectorDB, uid: uid, agentID: agentID, opts: opts}
+	if opts.Cursor != "" {
+		cursor, err := decodeSpeakerCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.SortBy != opts.SortBy || cursor.Desc != opts.Desc {
+			return nil, newError(ErrInvalidArgument, "invalid cursor: sort order does not match")
+		}
+		l.after = &SpeakerInfo{
+			ID:          cursor.ID,
+			AgentID:     cursor.AgentID,
+			Name:        cursor.Name,
+			SampleCount: cursor.SampleCount,
+			CreatedAt:   time.Unix(cursor.CreatedAt, 0),
+			UpdatedAt:   time.Unix(cursor.UpdatedAt, 0),
+		}
+	}
+
+	var (
+		speakers []*SpeakerInfo
+		err      error
+	)
+	switch opts.SortBy {
+	case SpeakerSortCreatedAt, SpeakerSortUpdatedAt:
+		speakers, err = l.listByTime()
+	case SpeakerSortName:
+		speakers, err = l.listByName()
+	default:
+		speakers, err = l.listByCount()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get speakers from vector database: %w", err)
+	}
+
+	page := &SpeakerPage{Speakers: speakers}
+	if opts.Limit > 0 && len(speakers) > opts.Limit {
+		page.Speakers = speakers[:opts.Limit]
+		page.HasMore = true
+		last := page.Speakers[opts.Limit-1]
+		page.NextCursor = encodeSpeakerCursor(speakerCursor{
+			SortBy:      opts.SortBy,
+			Desc:        opts.Desc,
+			ID:          last.ID,
+			AgentID:     last.AgentID,
+			Name:        last.Name,
+			SampleCount: last.SampleCount,
+			CreatedAt:   last.CreatedAt.Unix(),
+			UpdatedAt:   last.UpdatedAt.Unix(),
+		})
+	}
+
+	// 总数只在过滤条件都能由 Facet 结果判断时计算
+	if opts.NamePrefix == "" && opts.CreatedAfter.IsZero() && opts.CreatedBefore.IsZero() {
+		counts, err := l.sampleCounts()
+		if err != nil {
+			return nil, fmt.Errorf("failed to count speakers: %w", err)
+		}
+		total := 0
+		for _, count := range counts {
+			if count >= opts.MinSamples {
+				total++
+			}
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+// speakerLister 一次列表请求的状态
+type speakerLister struct {
+	db      *QdrantVectorDB
+	uid     string
+	agentID string
+	opts    SpeakerListOptions
+	after   *SpeakerInfo // 游标位置，为空表示第一页
+
+	counts map[speakerKey]int // Facet 统计的样本数（按需读取一次）
+}
+
+// want 返回需要的说话人数量（多取一个用于判断是否还有下一页），0 表示不限
+func (l *speakerLister) want() int {
+	if l.opts.Limit <= 0 {
+		return 0
+	}
+	return l.opts.Limit + 1
+}
+
+// full 判断是否已经凑够一页
+func (l *speakerLister) full(speakers []*SpeakerInfo) bool {
+	return l.want() > 0 && len(speakers) >= l.want()
+}
+
+// batchSize 返回下一批读取的数量
+func (l *speakerLister) batchSize(collected int) int {
+	if l.want() > 0 {
+		return min(speakerFetchBatch, l.want()-collected)
+	}
+	return speakerFetchBatch
+}
+
+// less 列表顺序（agent_id、speaker_id 作为第二、第三排序键，保证顺序稳定）
+func (l *speakerLister) less(a, b *SpeakerInfo) bool {
+	if c := compareSpeakers(a, b, l.opts.SortBy); c != 0 {
+		if l.opts.Desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	if a.ID != b.ID {
+		return a.ID < b.ID
+	}
+	return a.AgentID < b.AgentID
+}
+
+// afterCursor 判断说话人是否在游标之后
+func (l *speakerLister) afterCursor(info *SpeakerInfo) bool {
+	return l.after == nil || l.less(l.after, info)
+}
+
+// matches 判断说话人是否满足过滤条件
+func (l *speakerLister) matches(info *SpeakerInfo) bool {
+	opts := l.opts
+	if opts.NamePrefix != "" && !strings.HasPrefix(info.Name, opts.NamePrefix) {
+		return false
+	}
+	if !opts.CreatedAfter.IsZero() && info.CreatedAt.Before(opts.CreatedAfter) {
+		return false
+	}
+	if !opts.CreatedBefore.IsZero() && !info.CreatedAt.Before(opts.CreatedBefore) {
+		return false
+	}
+	return opts.MinSamples <= 0 || info.SampleCount >= opts.MinSamples
+}
+
+// sampleCounts 读取（并缓存）每个说话人的样本数
+func (l *speakerLister) sampleCounts() (map[speakerKey]int, error) {
+	if l.counts == nil {
+		counts, err := l.db.SpeakerSampleCounts(l.uid, l.agentID)
+		if err != nil {
+			return nil, err
+		}
+		l.counts = counts
+	}
+	return l.counts, nil
+}
+
+// listByCount 按 id 或 sample_count 排序：排序键都在 Facet 结果中，排序后从游标处分批读取详情
+func (l *speakerLister) listByCount() ([]*SpeakerInfo, error) {
+	counts, err := l.sampleCounts()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*SpeakerInfo, 0, len(counts))
+	for key, count := range counts {
+		candidate := &SpeakerInfo{ID: key.SpeakerID, AgentID: key.AgentID, SampleCount: count}
+		if count < l.opts.MinSamples || !l.afterCursor(candidate) {
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return l.less(candidates[i], candidates[j]) })
+
+	var speakers []*SpeakerInfo
+	for len(candidates) > 0 && !l.full(speakers) {
+		batch := candidates[:min(l.batchSize(len(speakers)), len(candidates))]
+		candidates = candidates[len(batch):]
+
+		ids := make([]string, 0, len(batch))
+		for _, candidate := range batch {
+			ids = append(ids, candidate.ID)
+		}
+		found, err := l.db.getSpeakersBy(l.uid, l.agentID, "speaker_id", ids)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, candidate := range batch {
+			info := found[speakerKey{UID: l.uid, AgentID: candidate.AgentID, SpeakerID: candidate.ID}]
+			if info == nil || !l.matches(info) {
+				continue // 统计之后被删除，或不满足过滤条件
+			}
+			speakers = append(speakers, info)
+		}
+	}
+	return speakers, nil
+}
+
+// listByName 按名称排序：Facet 得到全部名称，从游标处按名称分批读取说话人（同名说话人在同一批中）
+func (l *speakerLister) listByName() ([]*SpeakerInfo, error) {
+	var conditions []*qdrant.Condition
+	if filter := tenantFilter(l.uid, l.agentID); filter != nil {
+		conditions = filter.Must
+	}
+	values, err := l.db.facet("speaker_name", conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if !strings.HasPrefix(name, l.opts.NamePrefix) {
+			continue
+		}
+		// 游标所在的名称仍需读取（同名的其他说话人可能在游标之后）
+		if l.after != nil && (l.opts.Desc && name > l.after.Name || !l.opts.Desc && name < l.after.Name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if l.opts.Desc {
+			return names[i] > names[j]
+		}
+		return names[i] < names[j]
+	})
+
+	var speakers []*SpeakerInfo
+	for len(names) > 0 && !l.full(speakers) {
+		batch := names[:min(l.batchSize(len(speakers)), len(names))]
+		names = names[len(batch):]
+
+		found, err := l.db.getSpeakersBy(l.uid, l.agentID, "speaker_name", batch)
+		if err != nil {
+			return nil, err
+		}
+		matched := make([]*SpeakerInfo, 0, len(found))
+		for _, info := range found {
+			if l.afterCursor(info) && l.matches(info) {
+				matched = append(matched, info)
+			}
+		}
+		sort.Slice(matched, func(i, j int) bool { return l.less(matched[i], matched[j]) })
+		speakers = append(speakers, matched...)
+	}
+	if l.want() > 0 && len(speakers) > l.want() {
+		speakers = speakers[:l.want()]
+	}
+	return speakers, nil
+}
+
+// listByTime 按 created_at 或 updated_at 排序：按该字段顺序读取样本点流，读取新出现的说话人的详情
+// 说话人的创建时间是样本的最早创建时间、更新时间是最晚更新时间，样本点流中第一次出现时不一定已到达它的排序位置，
+// 因此只有流的位置越过说话人的排序键之后（之前的说话人都已出现）才能确定它的顺序
+func (l *speakerLister) listByTime() ([]*SpeakerInfo, error) {
+	field := l.opts.SortBy
+	sortKey := func(info *SpeakerInfo) int64 {
+		if field == SpeakerSortUpdatedAt {
+			return info.UpdatedAt.Unix()
+		}
+		return info.CreatedAt.Unix()
+	}
+	// before 判断 a 是否在流中位于 b 之前
+	before := func(a, b int64) bool {
+		if l.opts.Desc {
+			return a > b
+		}
+		return a < b
+	}
+
+	conditions := []*qdrant.Condition{qdrant.NewMatch("uid", l.uid)}
+	if l.agentID != "" {
+		conditions = append(conditions, qdrant.NewMatch("agent_id", l.agentID))
+	}
+	// 按创建时间排序时，创建时间范围可以作为样本点过滤条件（说话人的最早样本一定在范围内）
+	if field == SpeakerSortCreatedAt {
+		var createdRange qdrant.Range
+		if !l.opts.CreatedAfter.IsZero() {
+			gte := float64(l.opts.CreatedAfter.Unix())
+			createdRange.Gte = &gte
+		}
+		if !l.opts.CreatedBefore.IsZero() {
+			lt := float64(l.opts.CreatedBefore.Unix())
+			createdRange.Lt = &lt
+		}
+		if createdRange.Gte != nil || createdRange.Lt != nil {
+			conditions = append(conditions, qdrant.NewRange("created_at", &createdRange))
+		}
+	}
+
+	// 游标之前的说话人在游标的排序键之前必有样本点，从该位置开始读取即可
+	var startFrom *int64
+	if l.after != nil {
+		value := sortKey(l.after)
+		startFrom = &value
+	}
+
+	var (
+		exclude []*qdrant.PointId // 已读取的、取值等于 startFrom 的点
+		known   = make(map[speakerKey]*SpeakerInfo)
+		fetched = make(map[string]bool)
+	)
+	for {
+		points, err := l.db.scrollOrdered(conditions, field, l.opts.Desc, startFrom, exclude, speakerScrollBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		// 读取新出现的说话人的详情
+		var ids []string
+		for _, point := range points {
+			if point.Key.SpeakerID != "" && !fetched[point.Key.SpeakerID] {
+				fetched[point.Key.SpeakerID] = true
+				ids = append(ids, point.Key.SpeakerID)
+			}
+		}
+		for len(ids) > 0 {
+			batch := ids[:min(speakerFetchBatch, len(ids))]
+			ids = ids[len(batch):]
+			found, err := l.db.getSpeakersBy(l.uid, l.agentID, "speaker_id", batch)
+			if err != nil {
+				return nil, err
+			}
+			for key, info := range found {
+				if l.afterCursor(info) && l.matches(info) {
+					known[key] = info
+				}
+			}
+		}
+
+		exhausted := len(points) < speakerScrollBatch
+		var frontier int64
+		if !exhausted {
+			frontier = points[len(points)-1].Value
+			if startFrom == nil || *startFrom != frontier {
+				exclude = exclude[:0]
+			}
+			for _, point := range points {
+				if point.Value == frontier {
+					exclude = append(exclude, point.ID)
+				}
+			}
+			startFrom = &frontier
+		}
+
+		// 排序键在流的位置之前的说话人顺序已确定
+		ready := make([]*SpeakerInfo, 0, len(known))
+		for _, info := range known {
+			if exhausted || before(sortKey(info), frontier) {
+				ready = append(ready, info)
+			}
+		}
+		if exhausted || l.full(ready) {
+			sort.Slice(ready, func(i, j int) bool { return l.less(ready[i], ready[j]) })
+			if l.want() > 0 && len(ready) > l.want() {
+				ready = ready[:l.want()]
+			}
+			return ready, nil
+		}
+	}
+}
+
+// compareSpeakers 按排序字段比较两个说话人，返回 -1、0 或 1
+func compareSpeakers(a, b *SpeakerInfo, sortBy string) int {
+	switch sortBy {
+	case SpeakerSortUpdatedAt:
+		return compareInt64(a.UpdatedAt.Unix(), b.UpdatedAt.Unix())
+	case SpeakerSortName:
+		return strings.Compare(a.Name, b.Name)
+	case SpeakerSortSampleCount:
+		return compareInt64(int64(a.SampleCount), int64(b.SampleCount))
+	case SpeakerSortID:
+		return strings.Compare(a.ID, b.ID)
+	default:
+		return compareInt64(a.CreatedAt.Unix(), b.CreatedAt.Unix())
+	}
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// encodeSpeakerCursor 将游标编码为 URL 安全的字符串
+func encodeSpeakerCursor(cursor speakerCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeSpeakerCursor 解析客户端传入的游标
+func decodeSpeakerCursor(s string) (*speakerCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, newError(ErrInvalidArgument, "invalid cursor: %v", err)
+	}
+	var cursor speakerCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, newError(ErrInvalidArgument, "invalid cursor: %v", err)
+	}
+	return &cursor, nil
+}
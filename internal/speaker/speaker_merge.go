@@ -0,0 +1,264 @@
+package speaker
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"voice_server/internal/logger"
+)
+
+// MergeOptions 合并说话人参数
+type MergeOptions struct {
+	TargetSpeakerID string  `json:"target_speaker_id"` // 保留的说话人（A）
+	SourceSpeakerID string  `json:"source_speaker_id"` // 并入 A 后消失的说话人（B）
+	MinSimilarity   float32 `json:"min_similarity"`    // 两个说话人质心的最低余弦相似度，<= 0 时使用识别阈值
+	Force           bool    `json:"force"`             // 质心相似度低于 MinSimilarity 时仍然合并
+}
+
+// MergeReport 合并结果
+type MergeReport struct {
+	TargetSpeakerID    string  `json:"target_speaker_id"`
+	SourceSpeakerID    string  `json:"source_speaker_id"`
+	SpeakerName        string  `json:"speaker_name"`
+	UUID               string  `json:"uuid"`
+	SamplesMoved       int     `json:"samples_moved"`
+	SampleIndices      []int   `json:"sample_indices"` // 并入的样本在 A 下的新序号
+	SampleCount        int     `json:"sample_count"`   // 合并后 A 的样本数
+	CentroidSimilarity float32 `json:"centroid_similarity"`
+}
+
+// SplitOptions 拆分说话人参数
+type SplitOptions struct {
+	SampleIndices  []int  `json:"sample_indices"`   // 要移出的样本序号
+	NewSpeakerID   string `json:"new_speaker_id"`   // 新说话人 ID，不能已存在
+	NewSpeakerName string `json:"new_speaker_name"` // 为空时与 NewSpeakerID 相同
+	UUID           string `json:"uuid"`             // 新说话人的 UUID，为空时自动生成
+}
+
+// SplitReport 拆分结果
+type SplitReport struct {
+	SpeakerID          string  `json:"speaker_id"`
+	NewSpeakerID       string  `json:"new_speaker_id"`
+	NewSpeakerName     string  `json:"new_speaker_name"`
+	UUID               string  `json:"uuid"`
+	SamplesMoved       int     `json:"samples_moved"`
+	RemainingSamples   int     `json:"remaining_samples"`
+	CentroidSimilarity float32 `json:"centroid_similarity"` // 移出的样本与保留的样本质心之间的相似度
+}
+
+// MergeSpeakers 将说话人 B 并入说话人 A：B 的样本改为 A 的 speaker_id/speaker_name/uuid，
+// 样本序号接在 A 已有样本之后重新分配（A 原有样本序号不变），写回时沿用原 Point ID，向量不变
+// 冲突检测：两者属于不同 agent_id 时拒绝；质心相似度低于阈值时除非 Force 否则拒绝
+func (m *Manager) MergeSpeakers(uid, agentID string, opts MergeOptions) (*MergeReport, error) {
+	if uid == "" {
+		return nil, newError(ErrInvalidArgument, "uid is required")
+	}
+	targetID, sourceID := opts.TargetSpeakerID, opts.SourceSpeakerID
+	if targetID == "" || sourceID == "" {
+		return nil, newError(ErrInvalidArgument, "target_speaker_id and source_speaker_id are required")
+	}
+	if targetID == sourceID {
+		return nil, newError(ErrInvalidArgument, "cannot merge speaker %s into itself", targetID)
+	}
+
+	unlock := m.lockSpeakers(uid, targetID, sourceID)
+	defer unlock()
+
+	target, err := m.speakerPoints(uid, agentID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := m.speakerPoints(uid, agentID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 未指定 agent_id 时目标可能跨多个 agent，此时无法确定合并到哪个说话人
+	targetAgent := payloadString(target[0].Payload, "agent_id")
+	for _, point := range target {
+		if payloadString(point.Payload, "agent_id") != targetAgent {
+			return nil, newError(ErrInvalidArgument, "speaker %s exists under multiple agents, agent_id is required", targetID)
+		}
+	}
+	for _, point := range source {
+		if payloadString(point.Payload, "agent_id") != targetAgent {
+			return nil, newError(ErrConflict, "speakers %s and %s belong to different agent_id", targetID, sourceID)
+		}
+	}
+
+	minSimilarity := opts.MinSimilarity
+	if minSimilarity <= 0 {
+		minSimilarity = m.GetThreshold()
+	}
+	similarity := dotProduct(pointsCentroid(target), pointsCentroid(source))
+	if similarity < minSimilarity && !opts.Force {
+		return nil, newError(ErrConflict, "speaker %s does not match %s (centroid similarity %.4f < %.4f), set force to merge anyway",
+			sourceID, targetID, similarity, minSimilarity)
+	}
+
+	speakerName := payloadString(target[0].Payload, "speaker_name")
+	uuid := payloadString(target[0].Payload, "uuid")
+	next := 0
+	for _, point := range target {
+		next = max(next, int(payloadInt(point.Payload, "sample_index"))+1)
+	}
+
+	now := time.Now().Unix()
+	indices := make([]int, 0, len(source))
+	for i := range source {
+		source[i].Payload["speaker_id"] = targetID
+		source[i].Payload["speaker_name"] = speakerName
+		source[i].Payload["uuid"] = uuid
+		source[i].Payload["sample_index"] = int64(next)
+		source[i].Payload["updated_at"] = now
+		indices = append(indices, next)
+		next++
+	}
+	if err := m.vectorDB.InsertPoints(source); err != nil {
+		return nil, fmt.Errorf("failed to re-tag samples: %w", err)
+	}
+
+	logger.Infof("Merged speaker %s into %s for uid %s, agent_id %s, samples moved: %d, centroid similarity: %.4f",
+		sourceID, targetID, uid, targetAgent, len(source), similarity)
+	return &MergeReport{
+		TargetSpeakerID:    targetID,
+		SourceSpeakerID:    sourceID,
+		SpeakerName:        speakerName,
+		UUID:               uuid,
+		SamplesMoved:       len(source),
+		SampleIndices:      indices,
+		SampleCount:        len(target) + len(source),
+		CentroidSimilarity: similarity,
+	}, nil
+}
+
+// SplitSpeaker 将说话人的部分样本移到新的说话人下，新说话人的样本序号从 0 重新分配
+// 冲突检测：新说话人 ID 已存在时拒绝；不允许移出全部样本（请使用改名）
+func (m *Manager) SplitSpeaker(uid, agentID, speakerID string, opts SplitOptions) (*SplitReport, error) {
+	if uid == "" {
+		return nil, newError(ErrInvalidArgument, "uid is required")
+	}
+	if opts.NewSpeakerID == "" {
+		return nil, newError(ErrInvalidArgument, "new_speaker_id is required")
+	}
+	if opts.NewSpeakerID == speakerID {
+		return nil, newError(ErrInvalidArgument, "new_speaker_id must differ from speaker_id")
+	}
+	if len(opts.SampleIndices) == 0 {
+		return nil, newError(ErrInvalidArgument, "sample_indices is required")
+	}
+	if opts.NewSpeakerName == "" {
+		opts.NewSpeakerName = opts.NewSpeakerID
+	}
+	if opts.UUID == "" {
+		id, err := newRandomID()
+		if err != nil {
+			return nil, err
+		}
+		opts.UUID = id
+	}
+
+	unlock := m.lockSpeakers(uid, speakerID, opts.NewSpeakerID)
+	defer unlock()
+
+	points, err := m.speakerPoints(uid, agentID, speakerID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := m.vectorDB.GetSpeakerSampleCount(uid, agentID, opts.NewSpeakerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check new speaker: %w", err)
+	}
+	if existing > 0 {
+		return nil, newError(ErrConflict, "speaker %s already exists", opts.NewSpeakerID)
+	}
+
+	selected := make(map[int]bool, len(opts.SampleIndices))
+	for _, index := range opts.SampleIndices {
+		selected[index] = true
+	}
+	var moved, remaining []StoredPoint
+	for _, point := range points {
+		index := int(payloadInt(point.Payload, "sample_index"))
+		if selected[index] {
+			moved = append(moved, point)
+			delete(selected, index)
+		} else {
+			remaining = append(remaining, point)
+		}
+	}
+	if len(selected) > 0 {
+		missing := make([]int, 0, len(selected))
+		for index := range selected {
+			missing = append(missing, index)
+		}
+		sort.Ints(missing)
+		return nil, newError(ErrNotFound, "samples %v of speaker %s not found", missing, speakerID)
+	}
+	if len(remaining) == 0 {
+		return nil, newError(ErrInvalidArgument, "cannot move all samples of speaker %s, rename it instead", speakerID)
+	}
+
+	now := time.Now().Unix()
+	for i := range moved {
+		moved[i].Payload["speaker_id"] = opts.NewSpeakerID
+		moved[i].Payload["speaker_name"] = opts.NewSpeakerName
+		moved[i].Payload["uuid"] = opts.UUID
+		moved[i].Payload["sample_index"] = int64(i)
+		moved[i].Payload["updated_at"] = now
+	}
+	if err := m.vectorDB.InsertPoints(moved); err != nil {
+		return nil, fmt.Errorf("failed to re-tag samples: %w", err)
+	}
+
+	similarity := dotProduct(pointsCentroid(moved), pointsCentroid(remaining))
+	logger.Infof("Split %d samples of speaker %s into new speaker %s for uid %s, agent_id %s",
+		len(moved), speakerID, opts.NewSpeakerID, uid, agentID)
+	return &SplitReport{
+		SpeakerID:          speakerID,
+		NewSpeakerID:       opts.NewSpeakerID,
+		NewSpeakerName:     opts.NewSpeakerName,
+		UUID:               opts.UUID,
+		SamplesMoved:       len(moved),
+		RemainingSamples:   len(remaining),
+		CentroidSimilarity: similarity,
+	}, nil
+}
+
+// lockSpeakers 按固定顺序获取两个说话人的锁，避免并发合并/拆分时死锁
+func (m *Manager) lockSpeakers(uid, a, b string) func() {
+	if a > b {
+		a, b = b, a
+	}
+	unlockA := m.lockSpeaker(uid, a)
+	unlockB := m.lockSpeaker(uid, b)
+	return func() {
+		unlockB()
+		unlockA()
+	}
+}
+
+// speakerPoints 读取说话人的全部样本点，按 sample_index 排序；不存在时返回 ErrNotFound
+func (m *Manager) speakerPoints(uid, agentID, speakerID string) ([]StoredPoint, error) {
+	points, err := m.vectorDB.GetSpeakerPoints(uid, agentID, speakerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read speaker %s: %w", speakerID, err)
+	}
+	if len(points) == 0 {
+		return nil, newError(ErrNotFound, "speaker %s not found", speakerID)
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return payloadInt(points[i].Payload, "sample_index") < payloadInt(points[j].Payload, "sample_index")
+	})
+	return points, nil
+}
+
+// pointsCentroid 计算样本质心（样本先归一化再平均，最后再次归一化）
+func pointsCentroid(points []StoredPoint) []float32 {
+	normalized := make([][]float32, len(points))
+	for i, point := range points {
+		normalized[i] = normalizeVector(point.Vector)
+	}
+	return normalizeVector(meanVector(normalized))
+}
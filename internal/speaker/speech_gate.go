@@ -0,0 +1,153 @@
+package speaker
+
+import (
+	"fmt"
+
+	"voice_server/config"
+	"voice_server/internal/logger"
+	"voice_server/internal/pool"
+)
+
+// StreamingConfig 流式识别配置
+type StreamingConfig struct {
+	MinSpeechMs        int `json:"min_speech_ms"`        // finish 时要求的最短净语音时长，默认 1000，-1 表示不限制
+	EdgePaddingMs      int `json:"edge_padding_ms"`      // 每段语音前后保留的静音，默认 100
+	MaxRegisterSeconds int `json:"max_register_seconds"` // 流式注册每个样本最多接收的音频时长，默认 60
+}
+
+// speechGate 流式 VAD 门控：音频到达时按帧检测，只保留语音帧及每段语音前后 padding 长度的静音
+// 持有一个 VAD 实例直到 close，TEN-VAD 是有状态的，同一路流需要使用同一个实例
+type speechGate struct {
+	vadPool  pool.VADPoolInterface
+	instance pool.VADInstanceInterface
+	tenVAD   *pool.TenVADInstance
+
+	hopSize  int
+	padding  int       // 语音前后保留的静音样本数
+	pending  []float32 // 不足一帧的剩余样本
+	preRoll  []float32 // 最近 padding 个静音样本，下一段语音开始时补回
+	hangover int       // 语音结束后还需保留的静音样本数
+
+	speechSamples int // 检测为语音的样本数（净语音时长）
+}
+
+// newSpeechGate 从 VAD 池获取实例创建门控；未配置 VAD 池或不是 TEN-VAD 时返回 nil（不做门控）
+func (m *Manager) newSpeechGate(sampleRate, paddingMs int) (*speechGate, error) {
+	if m.vadPool == nil {
+		return nil, nil
+	}
+
+	instance, err := m.vadPool.Get()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get VAD instance: %w", err)
+	}
+	tenVAD, ok := instance.(*pool.TenVADInstance)
+	if !ok {
+		m.vadPool.Put(instance)
+		logger.Warnf("Streaming VAD gating requires TEN-VAD, got %s, speech gating disabled", instance.GetType())
+		return nil, nil
+	}
+
+	hopSize := config.GlobalConfig.VAD.TenVAD.HopSize
+	if hopSize <= 0 {
+		hopSize = 256
+	}
+	return &speechGate{
+		vadPool:  m.vadPool,
+		instance: instance,
+		tenVAD:   tenVAD,
+		hopSize:  hopSize,
+		padding:  sampleRate * paddingMs / 1000,
+	}, nil
+}
+
+// gateSpeech 对整段音频做 VAD 门控，返回保留的样本和净语音样本数
+// 未启用门控（没有 VAD 池或不是 TEN-VAD）时原样返回，整段视为语音
+func (m *Manager) gateSpeech(audioData []float32, sampleRate, paddingMs int) ([]float32, int, error) {
+	gate, err := m.newSpeechGate(sampleRate, paddingMs)
+	if err != nil {
+		return nil, 0, err
+	}
+	if gate == nil {
+		return audioData, len(audioData), nil
+	}
+	defer gate.close()
+
+	out, err := gate.process(audioData, make([]float32, 0, len(audioData)))
+	if err != nil {
+		return nil, 0, err
+	}
+	if out, err = gate.flush(out); err != nil {
+		return nil, 0, err
+	}
+	return out, gate.speechSamples, nil
+}
+
+// process 处理一块音频，返回追加了保留样本的 out
+func (g *speechGate) process(samples, out []float32) ([]float32, error) {
+	g.pending = append(g.pending, samples...)
+	for len(g.pending) >= g.hopSize {
+		var err error
+		if out, err = g.processFrame(g.pending[:g.hopSize], out); err != nil {
+			return out, err
+		}
+		g.pending = g.pending[g.hopSize:]
+	}
+	// 剩余样本移到新的切片，避免底层数组无限增长
+	g.pending = append([]float32(nil), g.pending...)
+	return out, nil
+}
+
+// flush 处理不足一帧的剩余样本
+func (g *speechGate) flush(out []float32) ([]float32, error) {
+	if len(g.pending) == 0 {
+		return out, nil
+	}
+	out, err := g.processFrame(g.pending, out)
+	g.pending = nil
+	return out, err
+}
+
+// processFrame 对一帧做 VAD 判定：语音帧连同之前缓存的静音一起保留，静音帧只保留语音结束后的 padding
+func (g *speechGate) processFrame(frame, out []float32) ([]float32, error) {
+	int16Frame := make([]int16, len(frame))
+	for i, f := range frame {
+		if f > 1.0 {
+			f = 1.0
+		} else if f < -1.0 {
+			f = -1.0
+		}
+		int16Frame[i] = int16(f * 32767)
+	}
+	_, flag, err := pool.GetInstance().ProcessAudio(g.tenVAD.Handle, int16Frame)
+	if err != nil {
+		return out, fmt.Errorf("TEN-VAD ProcessAudio error: %w", err)
+	}
+
+	if flag == 1 {
+		out = append(out, g.preRoll...)
+		out = append(out, frame...)
+		g.preRoll = g.preRoll[:0]
+		g.hangover = g.padding
+		g.speechSamples += len(frame)
+		return out, nil
+	}
+
+	keep := min(g.hangover, len(frame))
+	out = append(out, frame[:keep]...)
+	g.hangover -= keep
+	g.preRoll = append(g.preRoll, frame[keep:]...)
+	if len(g.preRoll) > g.padding {
+		g.preRoll = append(g.preRoll[:0], g.preRoll[len(g.preRoll)-g.padding:]...)
+	}
+	return out, nil
+}
+
+// close 归还 VAD 实例
+func (g *speechGate) close() {
+	if g.instance != nil {
+		g.vadPool.Put(g.instance)
+		g.instance = nil
+		g.tenVAD = nil
+	}
+}
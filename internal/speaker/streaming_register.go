@@ -0,0 +1,100 @@
+package speaker
+
+import (
+	"sync"
+)
+
+// StreamingRegistrar 流式注册器：累积音频，完成时经 VAD 裁剪后作为说话人的一个样本写入
+type StreamingRegistrar struct {
+	manager     *Manager
+	uid         string
+	agentID     string
+	speakerID   string
+	speakerName string
+	uuid        string
+	audio       []float32 // 累积的音频数据，完成时才从池中获取 VAD 和提取器
+	sampleRate  int
+	mutex       sync.Mutex
+	isFinished  bool
+}
+
+// NewStreamingRegistrar 创建流式注册器，uid、agent_id、speaker_id、speaker_name、uuid 与上传注册接口含义相同
+func (m *Manager) NewStreamingRegistrar(uid, agentID, speakerID, speakerName, uuid string, sampleRate int) *StreamingRegistrar {
+	return &StreamingRegistrar{
+		manager:     m,
+		uid:         uid,
+		agentID:     agentID,
+		speakerID:   speakerID,
+		speakerName: speakerName,
+		uuid:        uuid,
+		audio:       make([]float32, 0),
+		sampleRate:  sampleRate,
+	}
+}
+
+// AcceptAudio 接收音频数据块（流式输入）
+func (sr *StreamingRegistrar) AcceptAudio(audioData []float32) error {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	if sr.isFinished {
+		return newError(ErrInvalidArgument, "stream already finished")
+	}
+
+	// 完成前不做 VAD，累积的是原始音频，限制时长避免单个连接占用过多内存
+	maxSeconds := sr.manager.streamingConfig.MaxRegisterSeconds
+	if len(sr.audio)+len(audioData) > maxSeconds*sr.sampleRate {
+		return newError(ErrInvalidArgument, "audio too long: at most %ds per sample", maxSeconds)
+	}
+
+	sr.audio = append(sr.audio, audioData...)
+	return nil
+}
+
+// FinishAndRegister 完成输入，VAD 过滤静音（保留前后100ms）并检查净语音时长后注册为一个样本
+// 返回样本信息和过滤后的音频（用于归档）；质量检查和重复声纹检测与上传注册相同
+func (sr *StreamingRegistrar) FinishAndRegister() (*SampleInfo, []float32, error) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	if sr.isFinished {
+		return nil, nil, newError(ErrInvalidArgument, "stream already finished")
+	}
+	sr.isFinished = true
+	defer sr.cleanup()
+
+	if len(sr.audio) == 0 {
+		return nil, nil, newError(ErrInsufficientAudio, "no audio received")
+	}
+
+	filteredAudio, speechSamples, err := sr.manager.gateSpeech(sr.audio, sr.sampleRate, 100)
+	if err != nil {
+		return nil, nil, err
+	}
+	speechDuration := float64(speechSamples) / float64(sr.sampleRate)
+	minSpeech := float64(sr.manager.streamingConfig.MinSpeechMs) / 1000
+	if minSpeech > 0 && speechDuration < minSpeech {
+		return nil, nil, newError(ErrInsufficientAudio, "insufficient speech: %.2fs detected, at least %.2fs required", speechDuration, minSpeech)
+	}
+	if len(filteredAudio) == 0 {
+		return nil, nil, newError(ErrInsufficientAudio, "no speech detected")
+	}
+
+	sample, err := sr.manager.RegisterSpeaker(sr.uid, sr.agentID, sr.speakerID, sr.speakerName, sr.uuid, filteredAudio, sr.sampleRate)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sample, filteredAudio, nil
+}
+
+// cleanup 清理资源
+func (sr *StreamingRegistrar) cleanup() {
+	sr.audio = nil
+}
+
+// Close 关闭流式注册器并释放资源
+func (sr *StreamingRegistrar) Close() {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	sr.cleanup()
+}
@@ -2,16 +2,27 @@ package speaker
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"fmt"
-	"hash/fnv"
 	"math"
+	"os"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"voice_server/internal/logger"
 
 	"github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // QdrantConfig Qdrant 配置
@@ -19,50 +30,526 @@ type QdrantConfig struct {
 	Host           string
 	Port           int
 	CollectionName string
+	ModelID        string // 声纹模型标识，写入每个样本点的 model 字段
+	LegacyModel    string // 没有 model 字段的旧数据来自哪个模型，为空时视为当前模型；与当前模型不同时拒绝启动
+	Resilience     QdrantResilienceConfig
+
+	// 托管 Qdrant 的鉴权和加密传输
+	APIKey             string
+	UseTLS             bool
+	CACertFile         string // 自签名证书的 CA（PEM），为空时使用系统根证书
+	InsecureSkipVerify bool   // 跳过证书校验，仅用于测试环境
 }
 
+// payloadIndexes 需要建立索引的 payload 字段
+// keyword：过滤条件和 Facet 聚合（uid、agent_id、speaker_id 统计说话人，speaker_name 按名称分页），speaker_key 按说话人分组搜索
+// integer：说话人列表按时间排序（order_by 要求字段有范围索引）
+var payloadIndexes = []struct {
+	field     string
+	fieldType qdrant.FieldType
+	dataType  qdrant.PayloadSchemaType
+}{
+	{"uid", qdrant.FieldType_FieldTypeKeyword, qdrant.PayloadSchemaType_Keyword},
+	{"agent_id", qdrant.FieldType_FieldTypeKeyword, qdrant.PayloadSchemaType_Keyword},
+	{"speaker_id", qdrant.FieldType_FieldTypeKeyword, qdrant.PayloadSchemaType_Keyword},
+	{"speaker_name", qdrant.FieldType_FieldTypeKeyword, qdrant.PayloadSchemaType_Keyword},
+	{"speaker_key", qdrant.FieldType_FieldTypeKeyword, qdrant.PayloadSchemaType_Keyword},
+	{"created_at", qdrant.FieldType_FieldTypeInteger, qdrant.PayloadSchemaType_Integer},
+	{"updated_at", qdrant.FieldType_FieldTypeInteger, qdrant.PayloadSchemaType_Integer},
+}
+
+// unknownModel 之前的版本给没有 model 字段的旧数据标记的模型标识，启动检查时按旧数据处理
+const unknownModel = "unknown"
+
+// facetLimit Facet 聚合返回的最大分组数（统计说话人数量时需要覆盖全部说话人）
+const facetLimit = 1000000
+
 // QdrantVectorDB Qdrant 向量数据库客户端
 type QdrantVectorDB struct {
 	client         *qdrant.Client
 	collectionName string
 	embeddingDim   int
+	modelID        string
+	legacyModel    string
+
+	// 调用超时、重试、熔断和就绪状态（与 withCollection 返回的客户端共享）
+	guard *qdrantGuard
+
+	// 后台重连
+	stop      chan struct{}
+	stopOnce  sync.Once
+	reconnect sync.WaitGroup
 }
 
 // SearchResult 搜索结果
 type SearchResult struct {
+	UID         string
+	AgentID     string
 	SpeakerID   string
 	SpeakerName string
 	Confidence  float32
 	Distance    float32
 	SampleIndex int
+	Embedding   []float32 // 仅在请求返回向量时填充
 }
 
 // NewQdrantVectorDB 创建 Qdrant 向量数据库客户端
+// Qdrant 暂时不可达时不返回错误，而是在后台按退避间隔重连，连上后再初始化 Collection；
+// 在此之前所有调用都会返回 "vector database not ready" 错误
 func NewQdrantVectorDB(config *QdrantConfig, embeddingDim int) (*QdrantVectorDB, error) {
-	// 连接 Qdrant
+	guard := newQdrantGuard(config.Resilience)
+
+	tlsConfig, err := qdrantTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// 创建客户端（gRPC 连接在第一次调用时才建立）
 	client, err := qdrant.NewClient(&qdrant.Config{
-		Host: config.Host,
-		Port: config.Port,
+		Host:      config.Host,
+		Port:      config.Port,
+		APIKey:    config.APIKey,
+		UseTLS:    config.UseTLS,
+		TLSConfig: tlsConfig,
+		// 版本检查在创建客户端时同步调用 Qdrant，改为在下面的健康检查中记录服务端版本
+		SkipCompatibilityCheck: true,
+		GrpcOptions:            []grpc.DialOption{grpc.WithChainUnaryInterceptor(guard.unaryInterceptor)},
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Qdrant: %v", err)
+		return nil, fmt.Errorf("failed to create Qdrant client: %w", err)
 	}
 
 	db := &QdrantVectorDB{
 		client:         client,
 		collectionName: config.CollectionName,
 		embeddingDim:   embeddingDim,
+		modelID:        config.ModelID,
+		legacyModel:    config.LegacyModel,
+		guard:          guard,
+		stop:           make(chan struct{}),
 	}
 
-	// 确保 Collection 存在
-	ctx := context.Background()
-	if err := db.ensureCollectionExists(ctx); err != nil {
-		return nil, err
+	ctx := initContext()
+	reply, err := client.HealthCheck(ctx)
+	if err != nil {
+		guard.recordInitError(err)
+		logger.Warnf("Qdrant at %s:%d is not reachable, retrying in background: %v", config.Host, config.Port, err)
+		db.reconnect.Add(1)
+		go db.reconnectLoop()
+		return db, nil
 	}
+	logger.Infof("Connected to Qdrant %s at %s:%d", reply.GetVersion(), config.Host, config.Port)
 
+	// Qdrant 可达时初始化失败（维度或模型不一致等）属于配置错误，直接返回
+	if err := db.initialize(ctx); err != nil {
+		client.Close()
+		return nil, err
+	}
 	return db, nil
 }
 
+// qdrantTLSConfig 根据配置构造 TLS 配置，未指定 CA 和跳过校验时返回 nil（使用客户端默认配置）
+func qdrantTLSConfig(config *QdrantConfig) (*tls.Config, error) {
+	if !config.UseTLS {
+		if config.APIKey != "" {
+			logger.Warnf("Qdrant API key is configured without TLS, it will be sent in plaintext")
+		}
+		return nil, nil
+	}
+	if config.CACertFile == "" && !config.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+	if config.InsecureSkipVerify {
+		logger.Warnf("Qdrant TLS certificate verification is disabled")
+	}
+	if config.CACertFile != "" {
+		pem, err := os.ReadFile(config.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Qdrant CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificate found in Qdrant CA certificate file %s", config.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// reconnectLoop 后台重连，直到初始化成功或客户端关闭
+func (db *QdrantVectorDB) reconnectLoop() {
+	defer db.reconnect.Done()
+	for attempt := 0; ; attempt++ {
+		select {
+		case <-db.stop:
+			return
+		case <-time.After(reconnectBackoff(attempt)):
+		}
+
+		ctx := initContext()
+		reply, err := db.client.HealthCheck(ctx)
+		if err != nil {
+			logger.Debugf("Qdrant still not reachable (attempt %d): %v", attempt+1, err)
+			continue
+		}
+		if err := db.initialize(ctx); err != nil {
+			db.guard.recordInitError(err)
+			logger.Errorf("Qdrant %s is reachable but initialization failed (attempt %d): %v", reply.GetVersion(), attempt+1, err)
+			continue
+		}
+		logger.Infof("✅ Connected to Qdrant %s, speaker vector database is ready", reply.GetVersion())
+		return
+	}
+}
+
+// initialize 初始化 Collection（创建、迁移旧 ID、模型检查、索引），成功后放行普通调用
+func (db *QdrantVectorDB) initialize(ctx context.Context) error {
+	// 确保 Collection 存在（同时校验向量参数并创建 payload 索引）
+	if err := db.ensureCollectionExists(ctx); err != nil {
+		return err
+	}
+
+	// 一次性迁移：将旧的数值 Point ID 改写为 UUID（已迁移的 Collection 不会产生任何写入）
+	migrated, err := db.migrateLegacyPointIDs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to migrate legacy point ids: %w", err)
+	}
+	if migrated > 0 {
+		logger.Infof("✅ Migrated %d points in collection '%s' to UUID point ids", migrated, db.collectionName)
+	}
+
+	// 拒绝混用不同模型的向量
+	if err := db.checkModel(ctx); err != nil {
+		return err
+	}
+
+	// 一次性补写：旧版本写入的样本点没有 speaker_key，不会出现在按说话人分组的搜索结果中
+	keyed, err := db.backfillSpeakerKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to backfill speaker keys: %w", err)
+	}
+	if keyed > 0 {
+		logger.Infof("✅ Added speaker_key to %d points in collection '%s'", keyed, db.collectionName)
+	}
+
+	db.guard.ready.Store(true)
+	return nil
+}
+
+// Status 返回 Qdrant 连接状态
+func (db *QdrantVectorDB) Status() *QdrantStatus {
+	return db.guard.status()
+}
+
+// checkModel 检查 Collection 中的样本点是否都来自当前模型
+// 没有 model 字段的旧数据视为 legacy_model 产生的，未配置 legacy_model 时视为当前模型（向量维度已在创建检查时校验）；
+// 旧数据来自其他模型时拒绝启动。所有检查通过后才补写 model 字段，拒绝启动的路径不修改 Collection
+func (db *QdrantVectorDB) checkModel(ctx context.Context) error {
+	if db.modelID == "" {
+		return nil
+	}
+
+	// 之前的版本会把没有 model 字段的旧数据标记为 unknown，同样按旧数据处理
+	legacyConditions := []*qdrant.Condition{qdrant.NewIsEmpty("model"), qdrant.NewMatch("model", unknownModel)}
+
+	exact := true
+	others, err := db.client.Count(ctx, &qdrant.CountPoints{
+		CollectionName: db.collectionName,
+		Filter: &qdrant.Filter{
+			MustNot: append([]*qdrant.Condition{qdrant.NewMatch("model", db.modelID)}, legacyConditions...),
+		},
+		Exact: &exact,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count points of other models: %w", err)
+	}
+	if others > 0 {
+		return fmt.Errorf("collection '%s' contains %d embeddings from a model other than %s, run migrate-model to re-enroll into a new collection",
+			db.collectionName, others, db.modelID)
+	}
+
+	legacy, err := db.client.Count(ctx, &qdrant.CountPoints{
+		CollectionName: db.collectionName,
+		Filter:         &qdrant.Filter{Should: legacyConditions},
+		Exact:          &exact,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count untagged points: %w", err)
+	}
+	if legacy == 0 {
+		return nil
+	}
+
+	legacyModel := db.legacyModel
+	if legacyModel == "" {
+		legacyModel = db.modelID
+		logger.Warnf("Collection '%s' has %d points without model tag, assuming they were produced by the current model %s (set speaker.vector_db.legacy_model if the model has changed)",
+			db.collectionName, legacy, db.modelID)
+	}
+	if legacyModel != db.modelID {
+		return fmt.Errorf("collection '%s' contains %d embeddings from legacy model %s (speaker.vector_db.legacy_model), current model is %s, run migrate-model to re-enroll into a new collection",
+			db.collectionName, legacy, legacyModel, db.modelID)
+	}
+
+	for _, condition := range legacyConditions {
+		if err := db.setModelTag(ctx, condition, db.modelID); err != nil {
+			return fmt.Errorf("failed to tag legacy points: %w", err)
+		}
+	}
+	logger.Infof("✅ Tagged %d legacy points in collection '%s' with model %s", legacy, db.collectionName, db.modelID)
+	return nil
+}
+
+// setModelTag 将符合条件的样本点的 model 字段改为 model
+func (db *QdrantVectorDB) setModelTag(ctx context.Context, condition *qdrant.Condition, model string) error {
+	wait := true
+	_, err := db.client.SetPayload(ctx, &qdrant.SetPayloadPoints{
+		CollectionName: db.collectionName,
+		Wait:           &wait,
+		Payload:        qdrant.NewValueMap(map[string]any{"model": model}),
+		PointsSelector: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+			Must: []*qdrant.Condition{condition},
+		}),
+	})
+	return err
+}
+
+// speakerGroupKey 说话人分组键：同一个 speaker_id 可以出现在不同的 uid/agent_id 下，它们是不同的说话人
+// 各部分加引号拼接，避免分隔符出现在 ID 中时产生歧义
+func speakerGroupKey(uid, agentID, speakerID string) string {
+	return strconv.Quote(uid) + "/" + strconv.Quote(agentID) + "/" + strconv.Quote(speakerID)
+}
+
+// backfillSpeakerKeys 为没有 speaker_key 的样本点补写该字段，返回补写的数量
+func (db *QdrantVectorDB) backfillSpeakerKeys(ctx context.Context) (int, error) {
+	limit := uint32(1000)
+	wait := true
+	filter := &qdrant.Filter{
+		Must: []*qdrant.Condition{qdrant.NewIsEmpty("speaker_key")},
+	}
+
+	total := 0
+	for {
+		// 补写后的点不再满足过滤条件，每次都从头读取
+		points, err := db.client.Scroll(ctx, &qdrant.ScrollPoints{
+			CollectionName: db.collectionName,
+			Filter:         filter,
+			Limit:          &limit,
+			WithPayload:    qdrant.NewWithPayloadInclude("uid", "agent_id", "speaker_id"),
+		})
+		if err != nil {
+			return total, fmt.Errorf("failed to scroll points: %w", err)
+		}
+		if len(points) == 0 {
+			return total, nil
+		}
+
+		groups := make(map[string][]*qdrant.PointId)
+		for _, point := range points {
+			payload := point.GetPayload()
+			key := speakerGroupKey(payload["uid"].GetStringValue(), payload["agent_id"].GetStringValue(), payload["speaker_id"].GetStringValue())
+			groups[key] = append(groups[key], point.GetId())
+		}
+		for key, ids := range groups {
+			if _, err := db.client.SetPayload(ctx, &qdrant.SetPayloadPoints{
+				CollectionName: db.collectionName,
+				Wait:           &wait,
+				Payload:        qdrant.NewValueMap(map[string]any{"speaker_key": key}),
+				PointsSelector: qdrant.NewPointsSelector(ids...),
+			}); err != nil {
+				return total, fmt.Errorf("failed to set speaker key: %w", err)
+			}
+		}
+		total += len(points)
+	}
+}
+
+// withCollection 返回共享同一连接、操作另一个 Collection 的客户端（不做创建和模型检查，用于迁移时读取源 Collection）
+func (db *QdrantVectorDB) withCollection(collectionName string) *QdrantVectorDB {
+	return &QdrantVectorDB{
+		client:         db.client,
+		collectionName: collectionName,
+		embeddingDim:   db.embeddingDim,
+		modelID:        db.modelID,
+		guard:          db.guard,
+	}
+}
+
+// dropCollection 删除当前 Collection（仅用于阈值校准的临时 Collection）
+func (db *QdrantVectorDB) dropCollection() error {
+	if err := db.client.DeleteCollection(context.Background(), db.collectionName); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return nil
+}
+
+// SwitchAlias 原子地将别名指向新的 Collection（别名已存在时在同一请求中删除旧指向）
+func (db *QdrantVectorDB) SwitchAlias(aliasName, collectionName string) error {
+	ctx := context.Background()
+
+	aliases, err := db.client.ListAliases(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list aliases: %w", err)
+	}
+
+	actions := make([]*qdrant.AliasOperations, 0, 2)
+	for _, alias := range aliases {
+		if alias.GetAliasName() == aliasName {
+			actions = append(actions, qdrant.NewAliasDelete(aliasName))
+			break
+		}
+	}
+	actions = append(actions, qdrant.NewAliasCreate(aliasName, collectionName))
+
+	if err := db.client.UpdateAliases(ctx, actions); err != nil {
+		return fmt.Errorf("failed to update aliases: %w", err)
+	}
+	return nil
+}
+
+// ExistingPointIDs 返回 pointIDs 中仍存在的样本点
+func (db *QdrantVectorDB) ExistingPointIDs(pointIDs []string) (map[string]bool, error) {
+	ctx := context.Background()
+
+	const batchSize = 256
+	existing := make(map[string]bool, len(pointIDs))
+	for start := 0; start < len(pointIDs); start += batchSize {
+		end := min(start+batchSize, len(pointIDs))
+		ids := make([]*qdrant.PointId, 0, end-start)
+		for _, id := range pointIDs[start:end] {
+			if num, err := strconv.ParseUint(id, 10, 64); err == nil {
+				ids = append(ids, qdrant.NewIDNum(num))
+			} else {
+				ids = append(ids, qdrant.NewIDUUID(id))
+			}
+		}
+
+		points, err := db.client.Get(ctx, &qdrant.GetPoints{
+			CollectionName: db.collectionName,
+			Ids:            ids,
+			WithPayload:    qdrant.NewWithPayload(false),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get points: %w", err)
+		}
+		for _, point := range points {
+			existing[pointIDString(point.GetId())] = true
+		}
+	}
+	return existing, nil
+}
+
+// pointIDString 将 Point ID 转换为字符串（UUID 或十进制数值）
+func pointIDString(id *qdrant.PointId) string {
+	if uuid := id.GetUuid(); uuid != "" {
+		return uuid
+	}
+	return strconv.FormatUint(id.GetNum(), 10)
+}
+
+// migrateLegacyPointIDs 将使用数值 ID 的 Point 改写为 UUID ID，向量和 payload 保持不变
+// 先写入新 Point 再删除旧 Point，迁移中断时不会丢失数据；新 ID 由旧 ID 确定性生成，可安全重复执行
+func (db *QdrantVectorDB) migrateLegacyPointIDs(ctx context.Context) (int, error) {
+	// 第一遍只扫描 ID，找出所有数值 ID
+	limit := uint32(1000)
+	var offset *qdrant.PointId
+	legacyIDs := make([]*qdrant.PointId, 0)
+	for {
+		points, nextOffset, err := db.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: db.collectionName,
+			Offset:         offset,
+			Limit:          &limit,
+			WithPayload:    qdrant.NewWithPayload(false),
+			WithVectors:    qdrant.NewWithVectors(false),
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to scroll points: %w", err)
+		}
+
+		for _, point := range points {
+			if _, ok := point.GetId().GetPointIdOptions().(*qdrant.PointId_Num); ok {
+				legacyIDs = append(legacyIDs, point.GetId())
+			}
+		}
+
+		if nextOffset == nil || len(points) == 0 {
+			break
+		}
+		offset = nextOffset
+	}
+
+	if len(legacyIDs) == 0 {
+		return 0, nil
+	}
+	logger.Infof("Collection '%s' has %d points with legacy numeric ids, migrating...", db.collectionName, len(legacyIDs))
+
+	// 第二遍分批读取完整数据并改写
+	const batchSize = 256
+	wait := true
+	migrated := 0
+	for start := 0; start < len(legacyIDs); start += batchSize {
+		end := start + batchSize
+		if end > len(legacyIDs) {
+			end = len(legacyIDs)
+		}
+		batch := legacyIDs[start:end]
+
+		points, err := db.client.Get(ctx, &qdrant.GetPoints{
+			CollectionName: db.collectionName,
+			Ids:            batch,
+			WithPayload:    qdrant.NewWithPayload(true),
+			WithVectors:    qdrant.NewWithVectors(true),
+		})
+		if err != nil {
+			return migrated, fmt.Errorf("failed to get points: %w", err)
+		}
+
+		newPoints := make([]*qdrant.PointStruct, 0, len(points))
+		oldIDs := make([]*qdrant.PointId, 0, len(points))
+		for _, point := range points {
+			vector := pointVector(point.GetVectors())
+			if len(vector) == 0 {
+				logger.Warnf("Skipping legacy point %d without vector", point.GetId().GetNum())
+				continue
+			}
+			newPoints = append(newPoints, &qdrant.PointStruct{
+				Id:      qdrant.NewIDUUID(legacyPointUUID(point.GetId().GetNum())),
+				Vectors: qdrant.NewVectors(vector...),
+				Payload: point.GetPayload(),
+			})
+			oldIDs = append(oldIDs, point.GetId())
+		}
+		if len(newPoints) == 0 {
+			continue
+		}
+
+		if _, err := db.client.Upsert(ctx, &qdrant.UpsertPoints{
+			CollectionName: db.collectionName,
+			Wait:           &wait,
+			Points:         newPoints,
+		}); err != nil {
+			return migrated, fmt.Errorf("failed to upsert migrated points: %w", err)
+		}
+
+		if _, err := db.client.Delete(ctx, &qdrant.DeletePoints{
+			CollectionName: db.collectionName,
+			Wait:           &wait,
+			Points:         qdrant.NewPointsSelectorIDs(oldIDs),
+		}); err != nil {
+			return migrated, fmt.Errorf("failed to delete legacy points: %w", err)
+		}
+
+		migrated += len(newPoints)
+	}
+
+	return migrated, nil
+}
+
 // normalizeVector 对向量进行 L2 归一化
 // 公式: v_normalized = v / ||v||
 // 当向量归一化后，点积 = 余弦相似度
@@ -86,17 +573,54 @@ func normalizeVector(v []float32) []float32 {
 	return normalized
 }
 
-// generatePointID 生成唯一的 Point ID
-func generatePointID(uid, agentID, speakerID string, sampleIndex int) uint64 {
-	hash := fnv.New64a()
-	hash.Write([]byte(fmt.Sprintf("%s:%s:%s:%d", uid, agentID, speakerID, sampleIndex)))
-	return hash.Sum64()
+// newRandomID 生成 128 位随机十六进制 ID（挑战、删除回执、识别轮次）
+func newRandomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// generatePointID 生成随机的 UUID（v4）作为 Point ID
+// 旧版本使用 uid:agent_id:speaker_id:sample_index 的 FNV-64 哈希，存在并发覆盖和跨租户哈希冲突的问题
+func generatePointID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate point id: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant RFC 4122
+	return formatUUID(b), nil
+}
+
+// legacyPointUUID 将旧的数值 Point ID 确定性地映射为 UUID（v5 风格，基于 SHA-1）
+// 确定性映射保证迁移中断后重新执行不会产生重复数据
+func legacyPointUUID(num uint64) string {
+	sum := sha1.Sum([]byte("voice_server:point:" + strconv.FormatUint(num, 10)))
+	var b [16]byte
+	copy(b[:], sum[:16])
+	b[6] = (b[6] & 0x0f) | 0x50 // version 5
+	b[8] = (b[8] & 0x3f) | 0x80 // variant RFC 4122
+	return formatUUID(b)
+}
+
+// formatUUID 将 16 字节格式化为标准 UUID 字符串
+func formatUUID(b [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
 }
 
 // ensureCollectionExists 确保 Collection 存在，如果不存在则创建
+// 已存在的 Collection 会校验向量维度和距离，并补建缺少的 payload 索引
 func (db *QdrantVectorDB) ensureCollectionExists(ctx context.Context) error {
-	_, err := db.client.GetCollectionInfo(ctx, db.collectionName)
-	if err != nil {
+	info, err := db.client.GetCollectionInfo(ctx, db.collectionName)
+	switch code := status.Code(err); {
+	case err == nil:
+		if err := db.validateVectorParams(info); err != nil {
+			return err
+		}
+		db.ensurePayloadIndexes(ctx, info.GetPayloadSchema())
+	case code == codes.NotFound:
 		// Collection 不存在，创建它
 		logger.Infof("Collection '%s' does not exist, creating it...", db.collectionName)
 		err = db.client.CreateCollection(ctx, &qdrant.CreateCollection{
@@ -107,66 +631,92 @@ func (db *QdrantVectorDB) ensureCollectionExists(ctx context.Context) error {
 			}),
 		})
 		if err != nil {
-			return fmt.Errorf("failed to create collection: %v", err)
+			return fmt.Errorf("failed to create collection: %w", err)
 		}
 		logger.Infof("✅ Collection '%s' created successfully", db.collectionName)
+		db.ensurePayloadIndexes(ctx, nil)
+	case code == codes.Unauthenticated || code == codes.PermissionDenied:
+		return fmt.Errorf("qdrant rejected access to collection '%s', check speaker.vector_db.api_key: %w", db.collectionName, err)
+	default:
+		// 连接失败等其他错误不代表 Collection 不存在
+		return fmt.Errorf("failed to get collection info: %w", err)
 	}
 	return nil
 }
 
-// Insert 插入 embedding 到向量数据库
-func (db *QdrantVectorDB) Insert(uid, agentID, speakerID, speakerName, uuid string, embedding []float32, sampleIndex int, createdAt, updatedAt int64) error {
+// validateVectorParams 校验已存在 Collection 的向量参数：必须是单个未命名向量、维度与模型一致、使用余弦距离
+func (db *QdrantVectorDB) validateVectorParams(info *qdrant.CollectionInfo) error {
+	params := info.GetConfig().GetParams().GetVectorsConfig().GetParams()
+	if params == nil {
+		return fmt.Errorf("collection '%s' uses named vectors, expected a single unnamed vector", db.collectionName)
+	}
+	// 维度不一致说明是其他模型创建的
+	if params.GetSize() != uint64(db.embeddingDim) {
+		return fmt.Errorf("collection '%s' has vector size %d but model %s produces %d, run migrate-model to re-enroll into a new collection",
+			db.collectionName, params.GetSize(), db.modelID, db.embeddingDim)
+	}
+	// 相似度阈值按余弦相似度设定，其他距离的分数不可比
+	if params.GetDistance() != qdrant.Distance_Cosine {
+		return fmt.Errorf("collection '%s' uses %s distance, expected Cosine", db.collectionName, params.GetDistance())
+	}
+	return nil
+}
+
+// Insert 插入 embedding 到向量数据库，返回生成的 Point ID
+func (db *QdrantVectorDB) Insert(uid, agentID, speakerID, speakerName, uuid string, embedding []float32, sampleIndex int, createdAt, updatedAt int64) (string, error) {
 	ctx := context.Background()
 
 	// 确保 Collection 存在（如果不存在则创建）
 	if err := db.ensureCollectionExists(ctx); err != nil {
-		return fmt.Errorf("failed to ensure collection exists: %v", err)
+		return "", fmt.Errorf("failed to ensure collection exists: %w", err)
 	}
 
 	// 注意：使用 Distance_Cosine 时，Qdrant 会自动对向量进行归一化
 	// 因此不需要在程序中手动归一化（即使传入的向量已经归一化，Qdrant 再次归一化也没问题）
 
 	// 生成唯一的 Point ID
-	pointID := generatePointID(uid, agentID, speakerID, sampleIndex)
+	pointID, err := generatePointID()
+	if err != nil {
+		return "", err
+	}
 
 	// 构建 Point
 	point := &qdrant.PointStruct{
-		Id:      qdrant.NewIDNum(pointID),
+		Id:      qdrant.NewIDUUID(pointID),
 		Vectors: qdrant.NewVectors(embedding...),
 		Payload: qdrant.NewValueMap(map[string]any{
 			"uid":          uid,
 			"agent_id":     agentID,
 			"speaker_id":   speakerID,
+			"speaker_key":  speakerGroupKey(uid, agentID, speakerID),
 			"speaker_name": speakerName,
 			"uuid":         uuid,
 			"sample_index": sampleIndex,
+			"model":        db.modelID,
 			"created_at":   createdAt,
 			"updated_at":   updatedAt,
 		}),
 	}
 
-	_, err := db.client.Upsert(ctx, &qdrant.UpsertPoints{
+	// 等待写入生效：调用方在说话人锁内分配 sample_index，下一次分配前的 Scroll 必须能看到这次写入
+	wait := true
+	_, err = db.client.Upsert(ctx, &qdrant.UpsertPoints{
 		CollectionName: db.collectionName,
+		Wait:           &wait,
 		Points:         []*qdrant.PointStruct{point},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to insert point: %v", err)
+		return "", fmt.Errorf("failed to insert point: %w", err)
 	}
 
-	return nil
-}
-
-// Search 搜索相似向量（按 UID 过滤）
-func (db *QdrantVectorDB) Search(uid string, queryEmbedding []float32, threshold float32, topK int) ([]SearchResult, error) {
-	return db.SearchWithOptionalFilters(uid, "", "", "", queryEmbedding, threshold, topK)
+	return pointID, nil
 }
 
-// SearchWithOptionalFilters 搜索相似向量（支持可选的 UID、agent_id、speaker_id 和 speaker_name 过滤）
-// uid: 用户ID，如果为空字符串则不作为过滤条件
-// agentID: Agent ID，如果为空字符串则不作为过滤条件
-// speakerID: 说话人ID，如果为空字符串则不作为过滤条件
-// speakerName: 说话人名称，如果为空字符串则不作为过滤条件
-func (db *QdrantVectorDB) SearchWithOptionalFilters(uid, agentID, speakerID, speakerName string, queryEmbedding []float32, threshold float32, topK int) ([]SearchResult, error) {
+// SearchGroupedBySpeaker 按说话人（uid、agent_id、speaker_id 组合）分组搜索相似向量，每个说话人只返回得分最高的样本
+// 这里不应用阈值过滤，由调用方决定接受或拒绝
+// topK: 返回的说话人数量（不是样本点数量）
+// withVectors: 是否同时返回命中样本的向量（用于分数归一化等需要注册向量的场景）
+func (db *QdrantVectorDB) SearchGroupedBySpeaker(uid, agentID, speakerID, speakerName string, queryEmbedding []float32, topK int, withVectors bool) ([]SearchResult, error) {
 	ctx := context.Background()
 
 	// 构建过滤条件（按 UID、agent_id、speaker_id 和 speaker_name 过滤，如果为空则不添加该条件）
@@ -184,200 +734,681 @@ func (db *QdrantVectorDB) SearchWithOptionalFilters(uid, agentID, speakerID, spe
 		conditions = append(conditions, qdrant.NewMatch("speaker_name", speakerName))
 	}
 
-	var filter *qdrant.Filter
-	if len(conditions) > 0 {
-		filter = &qdrant.Filter{
-			Must: conditions,
-		}
-	}
-
 	limit := uint64(topK)
 	if limit == 0 {
 		limit = 1
 	}
+	groupSize := uint64(1) // 每个说话人只需要最相似的一个样本
 
-	// 对 queryEmbedding 进行 L2 归一化（DOT 距离要求向量归一化）
-	normalizedQueryEmbedding := normalizeVector(queryEmbedding)
-
-	// 使用 Query API 搜索
-	queryPoints := &qdrant.QueryPoints{
+	queryGroups := &qdrant.QueryPointGroups{
 		CollectionName: db.collectionName,
-		Query:          qdrant.NewQuery(normalizedQueryEmbedding...),
+		Query:          qdrant.NewQuery(normalizeVector(queryEmbedding)...),
+		GroupBy:        "speaker_key",
+		GroupSize:      &groupSize,
 		Limit:          &limit,
 		WithPayload:    qdrant.NewWithPayload(true),
+		WithVectors:    qdrant.NewWithVectors(withVectors),
 	}
-	if filter != nil {
-		queryPoints.Filter = filter
-	}
-
-	// 打印 queryPoints 信息
-	logger.Debugf("QueryPoints: CollectionName=%s, Limit=%d, WithPayload=%v, QueryEmbeddingLen=%d",
-		queryPoints.CollectionName, *queryPoints.Limit, queryPoints.WithPayload, len(normalizedQueryEmbedding))
-	if filter != nil {
-		logger.Debugf("  Filter: HasFilter=true, MustConditionsCount=%d", len(filter.Must))
-		for i, condition := range filter.Must {
-			logger.Debugf("    Filter.Must[%d]: %+v", i, condition)
+	if len(conditions) > 0 {
+		queryGroups.Filter = &qdrant.Filter{
+			Must: conditions,
 		}
-	} else {
-		logger.Debugf("  Filter: HasFilter=false")
 	}
 
-	searchPoints, err := db.client.Query(ctx, queryPoints)
+	groups, err := db.client.QueryGroups(ctx, queryGroups)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search: %v", err)
+		return nil, fmt.Errorf("failed to search groups: %w", err)
 	}
 
-	// 转换结果
-	results := make([]SearchResult, 0)
-	for _, point := range searchPoints {
-		if point.Payload == nil {
+	// 转换结果（Qdrant 按组内最高得分降序返回分组）
+	results := make([]SearchResult, 0, len(groups))
+	for _, group := range groups {
+		if len(group.Hits) == 0 {
 			continue
 		}
-
+		point := group.Hits[0]
 		payload := point.GetPayload()
-		var speakerID string
-		var speakerName string
+
+		var foundSpeakerID string
+		var foundSpeakerName string
 		var sampleIndex int
 
 		if val, ok := payload["speaker_id"]; ok {
-			speakerID = val.GetStringValue()
+			foundSpeakerID = val.GetStringValue()
 		}
 		if val, ok := payload["speaker_name"]; ok {
-			speakerName = val.GetStringValue()
+			foundSpeakerName = val.GetStringValue()
 		}
 		if val, ok := payload["sample_index"]; ok {
 			sampleIndex = int(val.GetIntegerValue())
 		}
 
-		// Query API 返回的 score 是余弦相似度（范围 [-1, 1]）
-		// 使用 Distance_Cosine 时，Qdrant 会自动归一化向量并计算余弦相似度
-		score := float32(point.Score)
-
-		// 重要：Manager 的 cosineSimilarity() 直接返回余弦相似度（范围 [-1, 1]）
-		// 为了与 Manager 保持一致，Qdrant 也应该直接使用 score，不做转换
-		var confidence float32
-		if score < -1 {
+		confidence := float32(point.Score)
+		if confidence < -1 {
 			confidence = -1.0
-		} else if score > 1 {
+		} else if confidence > 1 {
 			confidence = 1.0
-		} else {
-			// 直接使用 score（范围 [-1, 1]），与 Manager 的余弦相似度保持一致
-			confidence = score
 		}
 
-		// 应用阈值过滤
-		if confidence < threshold {
-			continue
-		}
+		results = append(results, SearchResult{
+			SpeakerID:   foundSpeakerID,
+			SpeakerName: foundSpeakerName,
+			Confidence:  confidence,
+			Distance:    1.0 - confidence,
+			SampleIndex: sampleIndex,
+			Embedding:   pointVector(point.GetVectors()),
+		})
+	}
+
+	return results, nil
+}
+
+// pointVector 从 Qdrant 返回的向量输出中提取稠密向量
+// 新版本服务端填充 Dense 字段，旧版本填充已废弃的 Data 字段，两者都需要兼容
+func pointVector(vectors *qdrant.VectorsOutput) []float32 {
+	vector := vectors.GetVector()
+	if vector == nil {
+		return nil
+	}
+	if dense := vector.GetDense(); dense != nil {
+		return dense.GetData()
+	}
+	return vector.GetData()
+}
+
+// LoadCollectionVectors 分页读取指定 Collection 中的全部向量（用于加载分数归一化的冒名者集合等）
+func (db *QdrantVectorDB) LoadCollectionVectors(collectionName string) ([][]float32, error) {
+	ctx := context.Background()
+
+	limit := uint32(1000)
+	var offset *qdrant.PointId
+	vectors := make([][]float32, 0)
+	for {
+		points, nextOffset, err := db.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: collectionName,
+			Offset:         offset,
+			Limit:          &limit,
+			WithPayload:    qdrant.NewWithPayload(false),
+			WithVectors:    qdrant.NewWithVectors(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scroll collection %s: %w", collectionName, err)
+		}
+
+		for _, point := range points {
+			if vector := pointVector(point.GetVectors()); len(vector) > 0 {
+				vectors = append(vectors, vector)
+			}
+		}
+
+		if nextOffset == nil || len(points) == 0 {
+			break
+		}
+		offset = nextOffset
+	}
+
+	return vectors, nil
+}
+
+// GetSpeakerSampleCount 获取说话人的样本数量（Count 接口，不读取样本点）
+func (db *QdrantVectorDB) GetSpeakerSampleCount(uid, agentID, speakerID string) (int, error) {
+	ctx := context.Background()
+
+	conditions := []*qdrant.Condition{
+		qdrant.NewMatch("uid", uid),
+		qdrant.NewMatch("speaker_id", speakerID),
+	}
+	// 如果 agentID 不为空，则添加到过滤条件
+	if agentID != "" {
+		conditions = append(conditions, qdrant.NewMatch("agent_id", agentID))
+	}
+
+	exact := true
+	count, err := db.client.Count(ctx, &qdrant.CountPoints{
+		CollectionName: db.collectionName,
+		Filter:         &qdrant.Filter{Must: conditions},
+		Exact:          &exact,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count points: %w", err)
+	}
+
+	return int(count), nil
+}
+
+// getSpeakersBy 读取 field 取值在 values 中的说话人（field 为 speaker_id 或 speaker_name），只读取这些说话人的样本点
+func (db *QdrantVectorDB) getSpeakersBy(uid, agentID, field string, values []string) (map[speakerKey]*SpeakerInfo, error) {
+	conditions := []*qdrant.Condition{
+		qdrant.NewMatch("uid", uid),
+		qdrant.NewMatchKeywords(field, values...),
+	}
+	if agentID != "" {
+		conditions = append(conditions, qdrant.NewMatch("agent_id", agentID))
+	}
+	return db.scrollSpeakers(&qdrant.Filter{Must: conditions})
+}
+
+// scrollSpeakers 分页读取满足过滤条件的样本点并按 (agent_id, speaker_id) 聚合为说话人
+// 说话人的创建时间取样本的最早创建时间，更新时间取样本的最晚更新时间
+func (db *QdrantVectorDB) scrollSpeakers(filter *qdrant.Filter) (map[speakerKey]*SpeakerInfo, error) {
+	ctx := context.Background()
+
+	limit := uint32(1000)
+	var offset *qdrant.PointId
+	speakers := make(map[speakerKey]*SpeakerInfo)
+	for {
+		points, nextOffset, err := db.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: db.collectionName,
+			Filter:         filter,
+			Offset:         offset,
+			Limit:          &limit,
+			WithPayload:    qdrant.NewWithPayloadInclude("uid", "speaker_id", "speaker_name", "uuid", "agent_id", "created_at", "updated_at"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scroll points: %w", err)
+		}
+
+		for _, point := range points {
+			payload := point.GetPayload()
+			key := speakerKey{
+				UID:       payload["uid"].GetStringValue(),
+				AgentID:   payload["agent_id"].GetStringValue(),
+				SpeakerID: payload["speaker_id"].GetStringValue(),
+			}
+			if key.SpeakerID == "" {
+				continue
+			}
+			createdAt := payload["created_at"].GetIntegerValue()
+			updatedAt := payload["updated_at"].GetIntegerValue()
+
+			info, exists := speakers[key]
+			if !exists {
+				info = &SpeakerInfo{
+					ID:        key.SpeakerID,
+					Name:      payload["speaker_name"].GetStringValue(),
+					UUID:      payload["uuid"].GetStringValue(),
+					AgentID:   key.AgentID,
+					CreatedAt: time.Unix(createdAt, 0),
+					UpdatedAt: time.Unix(updatedAt, 0),
+				}
+				speakers[key] = info
+			}
+
+			info.SampleCount++
+
+			// 更新最早创建时间和最晚更新时间
+			if createdAt > 0 && (info.CreatedAt.Unix() <= 0 || createdAt < info.CreatedAt.Unix()) {
+				info.CreatedAt = time.Unix(createdAt, 0)
+			}
+			if updatedAt > info.UpdatedAt.Unix() {
+				info.UpdatedAt = time.Unix(updatedAt, 0)
+			}
+		}
+
+		if nextOffset == nil || len(points) == 0 {
+			break
+		}
+		offset = nextOffset
+	}
+
+	return speakers, nil
+}
+
+// orderedPoint 按整数 payload 字段排序读取的样本点
+type orderedPoint struct {
+	ID    *qdrant.PointId
+	Key   speakerKey
+	Value int64
+}
+
+// scrollOrdered 按整数 payload 字段（created_at 或 updated_at）排序读取一批样本点
+// startFrom 不为空时从该值开始（包含等于该值的点），exclude 中的点不返回（用于跳过上一批末尾取值相同的点）
+func (db *QdrantVectorDB) scrollOrdered(conditions []*qdrant.Condition, field string, desc bool, startFrom *int64, exclude []*qdrant.PointId, limit uint32) ([]orderedPoint, error) {
+	ctx := context.Background()
+
+	direction := qdrant.Direction_Asc
+	if desc {
+		direction = qdrant.Direction_Desc
+	}
+	orderBy := &qdrant.OrderBy{Key: field, Direction: &direction}
+	if startFrom != nil {
+		orderBy.StartFrom = qdrant.NewStartFromInt(*startFrom)
+	}
+	filter := &qdrant.Filter{Must: conditions}
+	if len(exclude) > 0 {
+		filter.MustNot = []*qdrant.Condition{qdrant.NewHasID(exclude...)}
+	}
+
+	points, err := db.client.Scroll(ctx, &qdrant.ScrollPoints{
+		CollectionName: db.collectionName,
+		Filter:         filter,
+		Limit:          &limit,
+		OrderBy:        orderBy,
+		WithPayload:    qdrant.NewWithPayloadInclude("uid", "agent_id", "speaker_id", field),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scroll points ordered by %s: %w", field, err)
+	}
+
+	result := make([]orderedPoint, 0, len(points))
+	for _, point := range points {
+		payload := point.GetPayload()
+		result = append(result, orderedPoint{
+			ID: point.GetId(),
+			Key: speakerKey{
+				UID:       payload["uid"].GetStringValue(),
+				AgentID:   payload["agent_id"].GetStringValue(),
+				SpeakerID: payload["speaker_id"].GetStringValue(),
+			},
+			Value: payload[field].GetIntegerValue(),
+		})
+	}
+	return result, nil
+}
+
+// StoredPoint 与存储无关的样本点表示（用于导出、导入）
+type StoredPoint struct {
+	ID      string         `json:"-"` // 原 Point ID（导出文件不包含 ID，导入时重新生成，原 ID 记在 payload 的 source_point_id 中）
+	Payload map[string]any `json:"payload"`
+	Vector  []float32      `json:"vector"`
+}
+
+// ExportPoints 分页读取样本点的向量和 payload（uid、agentID 为空时不作为过滤条件，即导出整个 Collection）
+func (db *QdrantVectorDB) ExportPoints(uid, agentID string) ([]StoredPoint, error) {
+	return db.scrollPoints(tenantFilter(uid, agentID))
+}
+
+// GetSpeakerPoints 读取单个说话人全部样本点的向量和 payload（沿用原 Point ID，可修改 payload 后通过 InsertPoints 覆盖写回）
+func (db *QdrantVectorDB) GetSpeakerPoints(uid, agentID, speakerID string) ([]StoredPoint, error) {
+	conditions := []*qdrant.Condition{
+		qdrant.NewMatch("uid", uid),
+		qdrant.NewMatch("speaker_id", speakerID),
+	}
+	if agentID != "" {
+		conditions = append(conditions, qdrant.NewMatch("agent_id", agentID))
+	}
+	return db.scrollPoints(&qdrant.Filter{Must: conditions})
+}
+
+// scrollPoints 分页读取满足过滤条件的样本点
+func (db *QdrantVectorDB) scrollPoints(filter *qdrant.Filter) ([]StoredPoint, error) {
+	ctx := context.Background()
+
+	limit := uint32(1000)
+	var offset *qdrant.PointId
+	points := make([]StoredPoint, 0)
+	for {
+		batch, nextOffset, err := db.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: db.collectionName,
+			Filter:         filter,
+			Offset:         offset,
+			Limit:          &limit,
+			WithPayload:    qdrant.NewWithPayload(true),
+			WithVectors:    qdrant.NewWithVectors(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scroll points: %w", err)
+		}
+
+		for _, point := range batch {
+			vector := pointVector(point.GetVectors())
+			if len(vector) == 0 {
+				continue
+			}
+			payload := make(map[string]any, len(point.GetPayload()))
+			for key, value := range point.GetPayload() {
+				payload[key] = valueToInterface(value)
+			}
+			points = append(points, StoredPoint{ID: pointIDString(point.GetId()), Payload: payload, Vector: vector})
+		}
+
+		if nextOffset == nil || len(batch) == 0 {
+			break
+		}
+		offset = nextOffset
+	}
+
+	return points, nil
+}
+
+// SpeakerEmbeddings 单个说话人的全部样本向量
+type SpeakerEmbeddings struct {
+	AgentID     string
+	SpeakerID   string
+	SpeakerName string
+	Embeddings  [][]float32
+}
+
+// GetSpeakerEmbeddings 读取 uid/agentID 下所有说话人的样本向量，按 speaker_id、agent_id 排序
+func (db *QdrantVectorDB) GetSpeakerEmbeddings(uid, agentID string) ([]*SpeakerEmbeddings, error) {
+	return db.scrollSpeakerEmbeddings(tenantFilter(uid, agentID))
+}
+
+// GetSpeakerEmbedding 读取单个说话人的全部样本向量
+// 未指定 agentID 且该 speaker_id 存在于多个 agent 下时无法确定是哪个说话人，要求指定 agent_id
+func (db *QdrantVectorDB) GetSpeakerEmbedding(uid, agentID, speakerID string) (*SpeakerEmbeddings, error) {
+	conditions := []*qdrant.Condition{
+		qdrant.NewMatch("uid", uid),
+		qdrant.NewMatch("speaker_id", speakerID),
+	}
+	if agentID != "" {
+		conditions = append(conditions, qdrant.NewMatch("agent_id", agentID))
+	}
+
+	speakers, err := db.scrollSpeakerEmbeddings(&qdrant.Filter{Must: conditions})
+	if err != nil {
+		return nil, err
+	}
+	if len(speakers) == 0 {
+		return nil, newError(ErrNotFound, "speaker %s not found", speakerID)
+	}
+	if len(speakers) > 1 {
+		return nil, newError(ErrInvalidArgument, "speaker %s exists under %d agents, agent_id is required", speakerID, len(speakers))
+	}
+	return speakers[0], nil
+}
+
+// scrollSpeakerEmbeddings 分页读取满足过滤条件的样本向量并按 agent_id、speaker_id 分组（过滤条件需包含 uid）
+func (db *QdrantVectorDB) scrollSpeakerEmbeddings(filter *qdrant.Filter) ([]*SpeakerEmbeddings, error) {
+	ctx := context.Background()
+
+	type embeddingKey struct{ agentID, speakerID string }
+	limit := uint32(1000)
+	var offset *qdrant.PointId
+	speakers := make(map[embeddingKey]*SpeakerEmbeddings)
+	for {
+		batch, nextOffset, err := db.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: db.collectionName,
+			Filter:         filter,
+			Offset:         offset,
+			Limit:          &limit,
+			WithPayload:    qdrant.NewWithPayloadInclude("agent_id", "speaker_id", "speaker_name"),
+			WithVectors:    qdrant.NewWithVectors(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scroll points: %w", err)
+		}
+
+		for _, point := range batch {
+			vector := pointVector(point.GetVectors())
+			payload := point.GetPayload()
+			key := embeddingKey{agentID: payload["agent_id"].GetStringValue(), speakerID: payload["speaker_id"].GetStringValue()}
+			if len(vector) == 0 || key.speakerID == "" {
+				continue
+			}
+			speaker, ok := speakers[key]
+			if !ok {
+				speaker = &SpeakerEmbeddings{
+					AgentID:     key.agentID,
+					SpeakerID:   key.speakerID,
+					SpeakerName: payload["speaker_name"].GetStringValue(),
+				}
+				speakers[key] = speaker
+			}
+			speaker.Embeddings = append(speaker.Embeddings, vector)
+		}
+
+		if nextOffset == nil || len(batch) == 0 {
+			break
+		}
+		offset = nextOffset
+	}
+
+	result := make([]*SpeakerEmbeddings, 0, len(speakers))
+	for _, speaker := range speakers {
+		result = append(result, speaker)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SpeakerID != result[j].SpeakerID {
+			return result[i].SpeakerID < result[j].SpeakerID
+		}
+		return result[i].AgentID < result[j].AgentID
+	})
+	return result, nil
+}
+
+// InsertPoints 批量写入样本点（ID 为空时生成新的 UUID Point ID，否则沿用原 ID）
+func (db *QdrantVectorDB) InsertPoints(points []StoredPoint) error {
+	ctx := context.Background()
+
+	const batchSize = 256
+	wait := true
+	for start := 0; start < len(points); start += batchSize {
+		end := start + batchSize
+		if end > len(points) {
+			end = len(points)
+		}
+
+		batch := make([]*qdrant.PointStruct, 0, end-start)
+		for _, point := range points[start:end] {
+			pointID := point.ID
+			if pointID == "" {
+				var err error
+				if pointID, err = generatePointID(); err != nil {
+					return err
+				}
+			}
+			// speaker_id 等字段可能已被调用方改写（合并、拆分），按当前值重新生成分组键
+			point.Payload["speaker_key"] = speakerGroupKey(payloadString(point.Payload, "uid"),
+				payloadString(point.Payload, "agent_id"), payloadString(point.Payload, "speaker_id"))
+			payload, err := qdrant.TryValueMap(point.Payload)
+			if err != nil {
+				return fmt.Errorf("invalid payload: %w", err)
+			}
+			batch = append(batch, &qdrant.PointStruct{
+				Id:      qdrant.NewIDUUID(pointID),
+				Vectors: qdrant.NewVectors(point.Vector...),
+				Payload: payload,
+			})
+		}
+
+		if _, err := db.client.Upsert(ctx, &qdrant.UpsertPoints{
+			CollectionName: db.collectionName,
+			Wait:           &wait,
+			Points:         batch,
+		}); err != nil {
+			return fmt.Errorf("failed to insert points: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// valueToInterface 将 Qdrant payload 值转换为 Go 原生类型
+func valueToInterface(value *qdrant.Value) any {
+	switch kind := value.GetKind().(type) {
+	case *qdrant.Value_BoolValue:
+		return kind.BoolValue
+	case *qdrant.Value_IntegerValue:
+		return kind.IntegerValue
+	case *qdrant.Value_DoubleValue:
+		return kind.DoubleValue
+	case *qdrant.Value_StringValue:
+		return kind.StringValue
+	case *qdrant.Value_StructValue:
+		fields := make(map[string]any, len(kind.StructValue.GetFields()))
+		for key, field := range kind.StructValue.GetFields() {
+			fields[key] = valueToInterface(field)
+		}
+		return fields
+	case *qdrant.Value_ListValue:
+		values := make([]any, 0, len(kind.ListValue.GetValues()))
+		for _, item := range kind.ListValue.GetValues() {
+			values = append(values, valueToInterface(item))
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// CountSamples 统计样本点数量（uid、agentID 为空时不作为过滤条件）
+func (db *QdrantVectorDB) CountSamples(uid, agentID string) (int, error) {
+	ctx := context.Background()
+
+	exact := true
+	count, err := db.client.Count(ctx, &qdrant.CountPoints{
+		CollectionName: db.collectionName,
+		Filter:         tenantFilter(uid, agentID),
+		Exact:          &exact,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count points: %w", err)
+	}
+
+	return int(count), nil
+}
 
-		distance := 1.0 - confidence
+// speakerKey 说话人标识：同一 speaker_id 在不同 uid、agent_id 下是不同的说话人
+type speakerKey struct {
+	UID       string
+	AgentID   string
+	SpeakerID string
+}
 
-		results = append(results, SearchResult{
-			SpeakerID:   speakerID,
-			SpeakerName: speakerName,
-			Confidence:  confidence,
-			Distance:    distance,
-			SampleIndex: sampleIndex,
-		})
+// CountSpeakers 统计说话人数量（uid、agentID 为空时不作为过滤条件），按 (uid, agent_id, speaker_id) 去重
+func (db *QdrantVectorDB) CountSpeakers(uid, agentID string) (int, error) {
+	counts, err := db.SpeakerSampleCounts(uid, agentID)
+	if err != nil {
+		return 0, err
 	}
-
-	return results, nil
+	return len(counts), nil
 }
 
-// SearchWithFilter 搜索相似向量（按 UID、agent_id 和 speaker_id 过滤）
-func (db *QdrantVectorDB) SearchWithFilter(uid, agentID, speakerID string, queryEmbedding []float32, threshold float32, topK int) ([]SearchResult, error) {
-	ctx := context.Background()
+// SpeakerSampleCounts 统计每个说话人的样本数（uid、agentID 为空时不作为过滤条件）
+// 使用 Facet 聚合在服务端去重，只返回每个说话人一行，不需要读取样本点：
+// 未指定的 uid、agent_id 先按该字段 Facet 分组，再在每个分组内对 speaker_id 做 Facet
+func (db *QdrantVectorDB) SpeakerSampleCounts(uid, agentID string) (map[speakerKey]int, error) {
+	var groupBy []string
+	if uid == "" {
+		groupBy = append(groupBy, "uid")
+	}
+	if agentID == "" {
+		groupBy = append(groupBy, "agent_id")
+	}
 
-	// 构建过滤条件（按 UID、agent_id 和 speaker_id 过滤）
-	conditions := []*qdrant.Condition{
-		qdrant.NewMatch("uid", uid),
-		qdrant.NewMatch("speaker_id", speakerID),
+	var conditions []*qdrant.Condition
+	if filter := tenantFilter(uid, agentID); filter != nil {
+		conditions = filter.Must
 	}
-	// 如果 agentID 不为空，则添加到过滤条件
-	if agentID != "" {
-		conditions = append(conditions, qdrant.NewMatch("agent_id", agentID))
+
+	counts := make(map[speakerKey]int)
+	key := speakerKey{UID: uid, AgentID: agentID}
+	if err := db.facetSpeakers(conditions, groupBy, key, counts); err != nil {
+		return nil, err
 	}
-	filter := &qdrant.Filter{
-		Must: conditions,
+	return counts, nil
+}
+
+// facetSpeakers 按 groupBy 中的字段逐级分组，在最内层对 speaker_id 做 Facet，结果写入 counts
+func (db *QdrantVectorDB) facetSpeakers(conditions []*qdrant.Condition, groupBy []string, key speakerKey, counts map[speakerKey]int) error {
+	if len(groupBy) == 0 {
+		values, err := db.facet("speaker_id", conditions)
+		if err != nil {
+			return err
+		}
+		for speakerID, count := range values {
+			key.SpeakerID = speakerID
+			counts[key] += count
+		}
+		return nil
 	}
 
-	limit := uint64(topK)
-	if limit == 0 {
-		limit = 1
+	field := groupBy[0]
+	values, err := db.facet(field, conditions)
+	if err != nil {
+		return err
+	}
+	for value := range values {
+		group := key
+		switch field {
+		case "uid":
+			group.UID = value
+		case "agent_id":
+			group.AgentID = value
+		}
+		groupConditions := append(slices.Clip(conditions), qdrant.NewMatch(field, value))
+		if err := db.facetSpeakers(groupConditions, groupBy[1:], group, counts); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// 注意：使用 Distance_Cosine 时，Qdrant 会自动对查询向量进行归一化
-	// 因此不需要在程序中手动归一化（即使传入的向量已经归一化，Qdrant 再次归一化也没问题）
+// facet 对 keyword 字段做 Facet 聚合，返回每个取值的样本点数量
+func (db *QdrantVectorDB) facet(field string, conditions []*qdrant.Condition) (map[string]int, error) {
+	ctx := context.Background()
+
+	var filter *qdrant.Filter
+	if len(conditions) > 0 {
+		filter = &qdrant.Filter{Must: conditions}
+	}
 
-	// 使用 Query API 搜索
-	searchPoints, err := db.client.Query(ctx, &qdrant.QueryPoints{
+	exact := true
+	limit := uint64(facetLimit)
+	hits, err := db.client.Facet(ctx, &qdrant.FacetCounts{
 		CollectionName: db.collectionName,
-		Query:          qdrant.NewQuery(queryEmbedding...),
+		Key:            field,
 		Filter:         filter,
 		Limit:          &limit,
-		WithPayload:    qdrant.NewWithPayload(true),
+		Exact:          &exact,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to search: %v", err)
+		return nil, fmt.Errorf("failed to facet %s: %w", field, err)
 	}
 
-	// 转换结果（与 Search 方法相同）
-	results := make([]SearchResult, 0)
-	for _, point := range searchPoints {
-		if point.Payload == nil {
-			continue
-		}
-
-		payload := point.GetPayload()
-		var foundSpeakerID string
-		var speakerName string
-		var sampleIndex int
-
-		if val, ok := payload["speaker_id"]; ok {
-			foundSpeakerID = val.GetStringValue()
-		}
-		if val, ok := payload["speaker_name"]; ok {
-			speakerName = val.GetStringValue()
-		}
-		if val, ok := payload["sample_index"]; ok {
-			sampleIndex = int(val.GetIntegerValue())
-		}
+	values := make(map[string]int, len(hits))
+	for _, hit := range hits {
+		values[hit.GetValue().GetStringValue()] += int(hit.GetCount())
+	}
+	return values, nil
+}
 
-		// Query API 返回的 score 是余弦相似度（范围 [-1, 1]）
-		// 使用 Distance_Cosine 时，Qdrant 会自动归一化向量并计算余弦相似度
-		score := float32(point.Score)
-		// 重要：Manager 的 cosineSimilarity() 直接返回余弦相似度（范围 [-1, 1]）
-		// 为了与 Manager 保持一致，Qdrant 也应该直接使用 score，不做转换
-		var confidence float32
-		if score < -1 {
-			confidence = -1.0
-		} else if score > 1 {
-			confidence = 1.0
-		} else {
-			// 直接使用 score（范围 [-1, 1]），与 Manager 的余弦相似度保持一致
-			confidence = score
-		}
+// tenantFilter 构建按 uid 和 agent_id 过滤的条件（为空的字段不作为过滤条件）
+func tenantFilter(uid, agentID string) *qdrant.Filter {
+	conditions := make([]*qdrant.Condition, 0, 2)
+	if uid != "" {
+		conditions = append(conditions, qdrant.NewMatch("uid", uid))
+	}
+	if agentID != "" {
+		conditions = append(conditions, qdrant.NewMatch("agent_id", agentID))
+	}
+	if len(conditions) == 0 {
+		return nil
+	}
+	return &qdrant.Filter{
+		Must: conditions,
+	}
+}
 
-		if confidence < threshold {
+// ensurePayloadIndexes 创建缺少的 payload 索引（schema 为 Collection 现有的索引）
+// 索引创建失败只记录警告（缺少索引时 Facet 聚合和按时间排序的说话人列表会报错）
+func (db *QdrantVectorDB) ensurePayloadIndexes(ctx context.Context, schema map[string]*qdrant.PayloadSchemaInfo) {
+	wait := true
+	for _, index := range payloadIndexes {
+		if existing, ok := schema[index.field]; ok {
+			if existing.GetDataType() != index.dataType {
+				logger.Warnf("Payload index on '%s' in collection '%s' has type %s, expected %s", index.field, db.collectionName, existing.GetDataType(), index.dataType)
+			}
 			continue
 		}
-
-		distance := 1.0 - confidence
-
-		results = append(results, SearchResult{
-			SpeakerID:   foundSpeakerID,
-			SpeakerName: speakerName,
-			Confidence:  confidence,
-			Distance:    distance,
-			SampleIndex: sampleIndex,
+		fieldType := index.fieldType
+		_, err := db.client.CreateFieldIndex(ctx, &qdrant.CreateFieldIndexCollection{
+			CollectionName: db.collectionName,
+			Wait:           &wait,
+			FieldName:      index.field,
+			FieldType:      &fieldType,
 		})
+		if err != nil {
+			logger.Warnf("Failed to create %s index in collection '%s': %v", index.field, db.collectionName, err)
+			continue
+		}
+		logger.Infof("Created %s payload index on '%s' in collection '%s'", index.dataType, index.field, db.collectionName)
 	}
-
-	return results, nil
 }
 
-// GetSpeakerSampleCount 获取说话人的样本数量
-func (db *QdrantVectorDB) GetSpeakerSampleCount(uid, agentID, speakerID string) (int, error) {
+// DeleteSpeaker 删除说话人的所有向量（通过 speaker_id）
+func (db *QdrantVectorDB) DeleteSpeaker(uid, agentID, speakerID string) error {
 	ctx := context.Background()
 
 	// 使用 Scroll API 获取所有匹配的 points
@@ -393,28 +1424,53 @@ func (db *QdrantVectorDB) GetSpeakerSampleCount(uid, agentID, speakerID string)
 		Must: conditions,
 	}
 
-	limit := uint32(10000) // 足够大的值
+	limit := uint32(10000)
 	scrollResult, err := db.client.Scroll(ctx, &qdrant.ScrollPoints{
 		CollectionName: db.collectionName,
 		Filter:         filter,
 		Limit:          &limit,
-		WithPayload:    qdrant.NewWithPayload(true),
+		WithPayload:    qdrant.NewWithPayload(false), // 不需要 payload
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scroll points: %w", err)
+	}
+
+	if len(scrollResult) == 0 {
+		return nil // 没有数据需要删除
+	}
+
+	// 提取所有 Point IDs
+	ids := make([]*qdrant.PointId, 0, len(scrollResult))
+	for _, point := range scrollResult {
+		ids = append(ids, point.Id)
+	}
+
+	// 删除这些 points
+	_, err = db.client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: db.collectionName,
+		Points: &qdrant.PointsSelector{
+			PointsSelectorOneOf: &qdrant.PointsSelector_Points{
+				Points: &qdrant.PointsIdsList{
+					Ids: ids,
+				},
+			},
+		},
 	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to scroll points: %v", err)
+		return fmt.Errorf("failed to delete points: %w", err)
 	}
 
-	return len(scrollResult), nil
+	return nil
 }
 
-// GetSpeakerInfo 获取说话人信息
-func (db *QdrantVectorDB) GetSpeakerInfo(uid, agentID, speakerID string) (*SpeakerInfo, error) {
+// DeleteSpeakerByUUID 通过 UUID 删除说话人的所有向量
+func (db *QdrantVectorDB) DeleteSpeakerByUUID(uid, agentID, uuid string) error {
 	ctx := context.Background()
 
-	// 使用 Scroll API 获取所有匹配的 points
+	// 使用 Scroll API 获取所有匹配的 points（按 uuid 过滤）
 	conditions := []*qdrant.Condition{
 		qdrant.NewMatch("uid", uid),
-		qdrant.NewMatch("speaker_id", speakerID),
+		qdrant.NewMatch("uuid", uuid),
 	}
 	// 如果 agentID 不为空，则添加到过滤条件
 	if agentID != "" {
@@ -429,67 +1485,47 @@ func (db *QdrantVectorDB) GetSpeakerInfo(uid, agentID, speakerID string) (*Speak
 		CollectionName: db.collectionName,
 		Filter:         filter,
 		Limit:          &limit,
-		WithPayload:    qdrant.NewWithPayload(true),
+		WithPayload:    qdrant.NewWithPayload(false), // 不需要 payload
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to scroll points: %v", err)
+		return fmt.Errorf("failed to scroll points: %w", err)
 	}
 
 	if len(scrollResult) == 0 {
-		return nil, fmt.Errorf("speaker %s not found", speakerID)
+		return newError(ErrNotFound, "speaker with uuid %s not found for uid %s", uuid, uid)
 	}
 
-	// 从第一个 point 提取信息
-	firstPoint := scrollResult[0]
-	payload := firstPoint.GetPayload()
-
-	var speakerName string
-	var minCreatedAt, maxUpdatedAt int64 = -1, -1
-
-	if val, ok := payload["speaker_name"]; ok {
-		speakerName = val.GetStringValue()
-	}
-
-	// 遍历所有 points，找到最早的 created_at 和最新的 updated_at
+	// 提取所有 Point IDs
+	ids := make([]*qdrant.PointId, 0, len(scrollResult))
 	for _, point := range scrollResult {
-		payload := point.GetPayload()
-		if val, ok := payload["created_at"]; ok {
-			ts := val.GetIntegerValue()
-			if minCreatedAt == -1 || ts < minCreatedAt {
-				minCreatedAt = ts
-			}
-		}
-		if val, ok := payload["updated_at"]; ok {
-			ts := val.GetIntegerValue()
-			if ts > maxUpdatedAt {
-				maxUpdatedAt = ts
-			}
-		}
+		ids = append(ids, point.Id)
 	}
 
-	if minCreatedAt == -1 {
-		minCreatedAt = time.Now().Unix()
-	}
-	if maxUpdatedAt == -1 {
-		maxUpdatedAt = time.Now().Unix()
+	// 删除这些 points
+	_, err = db.client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: db.collectionName,
+		Points: &qdrant.PointsSelector{
+			PointsSelectorOneOf: &qdrant.PointsSelector_Points{
+				Points: &qdrant.PointsIdsList{
+					Ids: ids,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete points: %w", err)
 	}
 
-	return &SpeakerInfo{
-		ID:          speakerID,
-		Name:        speakerName,
-		SampleCount: len(scrollResult),
-		CreatedAt:   time.Unix(minCreatedAt, 0),
-		UpdatedAt:   time.Unix(maxUpdatedAt, 0),
-	}, nil
+	return nil
 }
 
-// GetAllSpeakers 获取指定 UID 和 Agent ID 的所有说话人列表
-func (db *QdrantVectorDB) GetAllSpeakers(uid, agentID string) ([]*SpeakerInfo, error) {
+// ListSpeakerSamples 获取说话人的所有样本元数据（按 sample_index 升序）
+func (db *QdrantVectorDB) ListSpeakerSamples(uid, agentID, speakerID string) ([]*SampleInfo, error) {
 	ctx := context.Background()
 
-	// 使用 Scroll API 获取所有匹配的 points
 	conditions := []*qdrant.Condition{
 		qdrant.NewMatch("uid", uid),
+		qdrant.NewMatch("speaker_id", speakerID),
 	}
 	// 如果 agentID 不为空，则添加到过滤条件
 	if agentID != "" {
@@ -507,87 +1543,47 @@ func (db *QdrantVectorDB) GetAllSpeakers(uid, agentID string) ([]*SpeakerInfo, e
 		WithPayload:    qdrant.NewWithPayload(true),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to scroll points: %v", err)
+		return nil, fmt.Errorf("failed to scroll points: %w", err)
 	}
 
-	// 按 speaker_id 聚合（注意：根据新设计，每个样本使用不同的 speaker_id，所以这里实际上每个 speaker_id 只有一个样本）
-	speakerMap := make(map[string]*SpeakerInfo)
+	samples := make([]*SampleInfo, 0, len(scrollResult))
 	for _, point := range scrollResult {
 		payload := point.GetPayload()
-		var speakerID string
-		var speakerName string
-		var uuid string
-		var agentID string
-		var createdAt, updatedAt int64
+		sample := &SampleInfo{PointID: pointIDString(point.GetId()), SpeakerID: speakerID}
 
-		if val, ok := payload["speaker_id"]; ok {
-			speakerID = val.GetStringValue()
+		if val, ok := payload["sample_index"]; ok {
+			sample.SampleIndex = int(val.GetIntegerValue())
 		}
 		if val, ok := payload["speaker_name"]; ok {
-			speakerName = val.GetStringValue()
+			sample.SpeakerName = val.GetStringValue()
 		}
 		if val, ok := payload["uuid"]; ok {
-			uuid = val.GetStringValue()
+			sample.UUID = val.GetStringValue()
 		}
 		if val, ok := payload["agent_id"]; ok {
-			agentID = val.GetStringValue()
+			sample.AgentID = val.GetStringValue()
 		}
 		if val, ok := payload["created_at"]; ok {
-			createdAt = val.GetIntegerValue()
+			sample.CreatedAt = time.Unix(val.GetIntegerValue(), 0)
 		}
 		if val, ok := payload["updated_at"]; ok {
-			updatedAt = val.GetIntegerValue()
+			sample.UpdatedAt = time.Unix(val.GetIntegerValue(), 0)
 		}
 
-		if speakerID == "" {
-			continue
-		}
-
-		info, exists := speakerMap[speakerID]
-		if !exists {
-			info = &SpeakerInfo{
-				ID:          speakerID,
-				Name:        speakerName,
-				UUID:        uuid,
-				AgentID:     agentID,
-				SampleCount: 0,
-				CreatedAt:   time.Unix(createdAt, 0),
-				UpdatedAt:   time.Unix(updatedAt, 0),
-			}
-			speakerMap[speakerID] = info
-		}
-
-		info.SampleCount++
-
-		// 更新最早创建时间和最晚更新时间
-		if createdAt > 0 {
-			pointCreatedAt := time.Unix(createdAt, 0)
-			if info.CreatedAt.IsZero() || pointCreatedAt.Before(info.CreatedAt) {
-				info.CreatedAt = pointCreatedAt
-			}
-		}
-		if updatedAt > 0 {
-			pointUpdatedAt := time.Unix(updatedAt, 0)
-			if info.UpdatedAt.IsZero() || pointUpdatedAt.After(info.UpdatedAt) {
-				info.UpdatedAt = pointUpdatedAt
-			}
-		}
+		samples = append(samples, sample)
 	}
 
-	// 转换为切片
-	speakers := make([]*SpeakerInfo, 0, len(speakerMap))
-	for _, info := range speakerMap {
-		speakers = append(speakers, info)
-	}
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].SampleIndex < samples[j].SampleIndex
+	})
 
-	return speakers, nil
+	return samples, nil
 }
 
-// DeleteSpeaker 删除说话人的所有向量（通过 speaker_id）
-func (db *QdrantVectorDB) DeleteSpeaker(uid, agentID, speakerID string) error {
+// UpdateSpeakerName 更新说话人所有样本的 speaker_name（同时刷新 updated_at）
+func (db *QdrantVectorDB) UpdateSpeakerName(uid, agentID, speakerID, speakerName string, updatedAt int64) error {
 	ctx := context.Background()
 
-	// 使用 Scroll API 获取所有匹配的 points
 	conditions := []*qdrant.Condition{
 		qdrant.NewMatch("uid", uid),
 		qdrant.NewMatch("speaker_id", speakerID),
@@ -596,57 +1592,34 @@ func (db *QdrantVectorDB) DeleteSpeaker(uid, agentID, speakerID string) error {
 	if agentID != "" {
 		conditions = append(conditions, qdrant.NewMatch("agent_id", agentID))
 	}
-	filter := &qdrant.Filter{
-		Must: conditions,
-	}
-
-	limit := uint32(10000)
-	scrollResult, err := db.client.Scroll(ctx, &qdrant.ScrollPoints{
-		CollectionName: db.collectionName,
-		Filter:         filter,
-		Limit:          &limit,
-		WithPayload:    qdrant.NewWithPayload(false), // 不需要 payload
-	})
-	if err != nil {
-		return fmt.Errorf("failed to scroll points: %v", err)
-	}
-
-	if len(scrollResult) == 0 {
-		return nil // 没有数据需要删除
-	}
-
-	// 提取所有 Point IDs
-	ids := make([]*qdrant.PointId, 0, len(scrollResult))
-	for _, point := range scrollResult {
-		ids = append(ids, point.Id)
-	}
 
-	// 删除这些 points
-	_, err = db.client.Delete(ctx, &qdrant.DeletePoints{
+	wait := true
+	_, err := db.client.SetPayload(ctx, &qdrant.SetPayloadPoints{
 		CollectionName: db.collectionName,
-		Points: &qdrant.PointsSelector{
-			PointsSelectorOneOf: &qdrant.PointsSelector_Points{
-				Points: &qdrant.PointsIdsList{
-					Ids: ids,
-				},
-			},
-		},
+		Wait:           &wait,
+		Payload: qdrant.NewValueMap(map[string]any{
+			"speaker_name": speakerName,
+			"updated_at":   updatedAt,
+		}),
+		PointsSelector: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+			Must: conditions,
+		}),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to delete points: %v", err)
+		return fmt.Errorf("failed to set payload: %w", err)
 	}
 
 	return nil
 }
 
-// DeleteSpeakerByUUID 通过 UUID 删除说话人的所有向量
-func (db *QdrantVectorDB) DeleteSpeakerByUUID(uid, agentID, uuid string) error {
+// DeleteSample 删除说话人的单个样本（通过 sample_index）
+func (db *QdrantVectorDB) DeleteSample(uid, agentID, speakerID string, sampleIndex int) error {
 	ctx := context.Background()
 
-	// 使用 Scroll API 获取所有匹配的 points（按 uuid 过滤）
 	conditions := []*qdrant.Condition{
 		qdrant.NewMatch("uid", uid),
-		qdrant.NewMatch("uuid", uuid),
+		qdrant.NewMatch("speaker_id", speakerID),
+		qdrant.NewMatchInt("sample_index", int64(sampleIndex)),
 	}
 	// 如果 agentID 不为空，则添加到过滤条件
 	if agentID != "" {
@@ -664,32 +1637,24 @@ func (db *QdrantVectorDB) DeleteSpeakerByUUID(uid, agentID, uuid string) error {
 		WithPayload:    qdrant.NewWithPayload(false), // 不需要 payload
 	})
 	if err != nil {
-		return fmt.Errorf("failed to scroll points: %v", err)
+		return fmt.Errorf("failed to scroll points: %w", err)
 	}
 
 	if len(scrollResult) == 0 {
-		return fmt.Errorf("speaker with uuid %s not found for uid %s", uuid, uid)
+		return newError(ErrNotFound, "sample %d of speaker %s not found", sampleIndex, speakerID)
 	}
 
-	// 提取所有 Point IDs
 	ids := make([]*qdrant.PointId, 0, len(scrollResult))
 	for _, point := range scrollResult {
 		ids = append(ids, point.Id)
 	}
 
-	// 删除这些 points
 	_, err = db.client.Delete(ctx, &qdrant.DeletePoints{
 		CollectionName: db.collectionName,
-		Points: &qdrant.PointsSelector{
-			PointsSelectorOneOf: &qdrant.PointsSelector_Points{
-				Points: &qdrant.PointsIdsList{
-					Ids: ids,
-				},
-			},
-		},
+		Points:         qdrant.NewPointsSelectorIDs(ids),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to delete points: %v", err)
+		return fmt.Errorf("failed to delete points: %w", err)
 	}
 
 	return nil
@@ -697,8 +1662,14 @@ func (db *QdrantVectorDB) DeleteSpeakerByUUID(uid, agentID, uuid string) error {
 
 // Close 关闭向量数据库连接
 func (db *QdrantVectorDB) Close() error {
-	// Qdrant Go Client 可能不需要显式关闭，但保留接口以便未来扩展
-	return nil
+	// withCollection 返回的客户端共享连接，由原客户端关闭
+	if db.stop == nil {
+		return nil
+	}
+	// 停止后台重连
+	db.stopOnce.Do(func() { close(db.stop) })
+	db.reconnect.Wait()
+	return db.client.Close()
 }
 
 // parseQdrantAddress 解析 Qdrant 地址（格式：host:port 或 host）
@@ -722,3 +1693,57 @@ func parseQdrantAddress(addr string) (string, int) {
 
 	return host, port
 }
+
+// ListTenantPoints 列出 uid/agentID 下所有样本点的 Point ID 及其 speaker_id
+func (db *QdrantVectorDB) ListTenantPoints(uid, agentID string) (map[string]string, error) {
+	if uid == "" {
+		return nil, newError(ErrInvalidArgument, "uid is required")
+	}
+	ctx := context.Background()
+
+	limit := uint32(1000)
+	var offset *qdrant.PointId
+	points := make(map[string]string)
+	for {
+		batch, nextOffset, err := db.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: db.collectionName,
+			Filter:         tenantFilter(uid, agentID),
+			Offset:         offset,
+			Limit:          &limit,
+			WithPayload:    qdrant.NewWithPayloadInclude("speaker_id"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scroll points: %w", err)
+		}
+
+		for _, point := range batch {
+			points[pointIDString(point.GetId())] = point.GetPayload()["speaker_id"].GetStringValue()
+		}
+
+		if nextOffset == nil || len(batch) == 0 {
+			break
+		}
+		offset = nextOffset
+	}
+
+	return points, nil
+}
+
+// DeleteTenant 删除 uid/agentID 下的所有样本点（uid 必填，避免误删整个 Collection）
+func (db *QdrantVectorDB) DeleteTenant(uid, agentID string) error {
+	if uid == "" {
+		return newError(ErrInvalidArgument, "uid is required")
+	}
+	ctx := context.Background()
+
+	wait := true
+	_, err := db.client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: db.collectionName,
+		Wait:           &wait,
+		Points:         qdrant.NewPointsSelectorFilter(tenantFilter(uid, agentID)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete points: %w", err)
+	}
+	return nil
+}
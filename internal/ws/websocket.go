@@ -14,6 +14,9 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// CodeRecognitionDisabled 语音识别未启用时错误消息的 code
+const CodeRecognitionDisabled = "recognition_disabled"
+
 // Upgrader 用于升级 WebSocket 连接
 var Upgrader = websocket.Upgrader{
 	CheckOrigin:       func(r *http.Request) bool { return true },
@@ -49,6 +52,7 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request, sessionManager *ses
 		logger.Warnf("Recognition is disabled, closing WebSocket connection")
 		conn.WriteJSON(map[string]interface{}{
 			"type":    "error",
+			"code":    CodeRecognitionDisabled,
 			"message": "Recognition service is disabled",
 		})
 		conn.Close()
@@ -113,6 +117,7 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request, sessionManager *ses
 					select {
 					case sess.SendQueue <- map[string]interface{}{
 						"type":    "error",
+						"code":    session.ErrorCode(err),
 						"message": err.Error(),
 					}:
 					default:
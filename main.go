@@ -16,6 +16,19 @@ import (
 )
 
 func main() {
+	// 子命令：eval（声纹阈值校准评估）、export / import（声纹库导出导入）、migrate-model（声纹模型迁移）
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "eval":
+			os.Exit(runEval(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "import":
+			os.Exit(runImport(os.Args[2:]))
+		case "migrate-model":
+			os.Exit(runMigrateModel(os.Args[2:]))
+		}
+	}
 
 	// 加载配置
 	if err := config.InitConfig("config.json"); err != nil {
@@ -23,17 +36,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	// 设置日志级别
-	logger.InitLoggerFromConfig(logger.LoggingConfig{
-		Level:      config.GlobalConfig.Logging.Level,
-		Format:     config.GlobalConfig.Logging.Format,
-		Output:     config.GlobalConfig.Logging.Output,
-		FilePath:   config.GlobalConfig.Logging.FilePath,
-		MaxSize:    config.GlobalConfig.Logging.MaxSize,
-		MaxBackups: config.GlobalConfig.Logging.MaxBackups,
-		MaxAge:     config.GlobalConfig.Logging.MaxAge,
-		Compress:   config.GlobalConfig.Logging.Compress,
-	})
+	initLogger()
 	logger.Infof("✅ Configuration loaded")
 	config.PrintConfig()
 
@@ -57,7 +60,9 @@ func main() {
 	// 优雅关闭
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		<-quit
 		logger.Infof("🛑 Shutting down server...")
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -65,6 +70,10 @@ func main() {
 		if err := server.Shutdown(ctx); err != nil {
 			logger.Errorf("Server forced to shutdown:%v", err)
 		}
+		// 请求处理完后再关闭声纹模块，发送完队列中的审计记录
+		if deps.SpeakerManager != nil {
+			deps.SpeakerManager.Close()
+		}
 		logger.Infof("✅ Server shutdown complete")
 	}()
 
@@ -78,4 +87,20 @@ func main() {
 		logger.Errorf("Server error:%v", err)
 		os.Exit(1)
 	}
+	// 等待关闭流程完成
+	<-shutdownDone
+}
+
+// initLogger 根据配置初始化日志
+func initLogger() {
+	logger.InitLoggerFromConfig(logger.LoggingConfig{
+		Level:      config.GlobalConfig.Logging.Level,
+		Format:     config.GlobalConfig.Logging.Format,
+		Output:     config.GlobalConfig.Logging.Output,
+		FilePath:   config.GlobalConfig.Logging.FilePath,
+		MaxSize:    config.GlobalConfig.Logging.MaxSize,
+		MaxBackups: config.GlobalConfig.Logging.MaxBackups,
+		MaxAge:     config.GlobalConfig.Logging.MaxAge,
+		Compress:   config.GlobalConfig.Logging.Compress,
+	})
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"voice_server/config"
+	"voice_server/internal/logger"
+	"voice_server/internal/speaker"
+)
+
+// runMigrateModel 声纹模型迁移子命令
+// 使用新模型（配置中的 speaker.model_path）从注册音频重新提取声纹，写入新的 Collection，成功后切换：
+//   - 指定 -alias 时，原子地将 Qdrant 别名指向新 Collection（服务通过别名访问时使用）
+//   - 否则将 speaker.vector_db.collection_name 写回配置文件，服务重启后生效
+//
+// 用法：voice_server migrate-model -target speaker_embeddings_v2 [-source speaker_embeddings] [-alias speakers] [-allow-missing] [-dry-run]
+func runMigrateModel(args []string) int {
+	fs := flag.NewFlagSet("migrate-model", flag.ContinueOnError)
+	configPath := fs.String("config", "config.json", "配置文件路径")
+	source := fs.String("source", "", "旧模型的 Collection（默认使用配置中的 collection_name）")
+	target := fs.String("target", "", "新模型的 Collection（必须为空或不存在）")
+	audioDir := fs.String("audio-dir", "", "注册音频目录（默认与注册音频保存目录一致）")
+	alias := fs.String("alias", "", "迁移成功后指向新 Collection 的 Qdrant 别名")
+	allowMissing := fs.Bool("allow-missing", false, "允许丢弃找不到注册音频的样本")
+	dryRun := fs.Bool("dry-run", false, "只检查注册音频是否齐全，不写入")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *target == "" {
+		fmt.Fprintln(os.Stderr, "migrate-model: -target is required")
+		fs.Usage()
+		return 2
+	}
+
+	if err := config.InitConfig(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "migrate-model: failed to load configuration: %v\n", err)
+		return 1
+	}
+	if *source == "" {
+		*source = config.GlobalConfig.Speaker.VectorDB.CollectionName
+		if envCollectionName := os.Getenv("QDRANT_COLLECTION_NAME"); envCollectionName != "" {
+			*source = envCollectionName
+		}
+	}
+	if *audioDir == "" {
+		*audioDir = speaker.RegisterAudioDir()
+	}
+
+	// 以迁移目标作为当前 Collection 初始化（源 Collection 属于旧模型，会被启动检查拒绝）
+	// 环境变量 QDRANT_COLLECTION_NAME 优先于配置文件，这里同时覆盖
+	config.GlobalConfig.Speaker.VectorDB.CollectionName = *target
+	os.Setenv("QDRANT_COLLECTION_NAME", *target)
+	deps, err := initCommandDeps("migrate-model", *configPath, false)
+	if err != nil {
+		return 1
+	}
+	defer deps.SpeakerManager.Close()
+
+	report, err := deps.SpeakerManager.MigrateModel(speaker.ModelMigrationOptions{
+		SourceCollection: *source,
+		AudioDir:         *audioDir,
+		AllowMissing:     *allowMissing,
+		DryRun:           *dryRun,
+	})
+	if report != nil {
+		output, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(output))
+	}
+	if err != nil {
+		logger.Errorf("Model migration failed: %v", err)
+		return 1
+	}
+	if *dryRun {
+		return 0
+	}
+
+	if *alias != "" {
+		if err := deps.SpeakerManager.SwitchCollectionAlias(*alias); err != nil {
+			logger.Errorf("Failed to switch alias: %v", err)
+			return 1
+		}
+		logger.Infof("✅ Alias '%s' now points to '%s'", *alias, *target)
+		return 0
+	}
+
+	if err := deps.HotReloadMgr.SetConfigValue("speaker.vector_db.collection_name", *target); err != nil {
+		logger.Errorf("Failed to update configuration: %v", err)
+		return 1
+	}
+	if err := deps.HotReloadMgr.SaveConfig(); err != nil {
+		logger.Errorf("Failed to save configuration: %v", err)
+		return 1
+	}
+	logger.Infof("✅ speaker.vector_db.collection_name set to '%s', restart the server to use the new model", *target)
+	return 0
+}